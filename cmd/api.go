@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"metrics.k8s.io/kube"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+HTTP JSON API，与CLI共用采集及排序逻辑，返回内容与 -o json 相同:
+  - GET /api/v1/nodes                    对应 kubetop node
+  - GET /api/v1/pods                     对应 kubetop pod -A
+  - GET /api/v1/namespaces/{ns}/pods     对应 kubetop pod -n {ns}
+查询参数: sortBy(同--sort-by)、labelSelector(同-l)、fieldSelector(同--field-selector)、
+container=true(同-c，仅pod)
+*/
+
+var podSortKeySet = map[string]bool{"cpu.request": true, "mem.request": true, "cpu.limit": true, "mem.limit": true}
+
+// 参数错误
+var errBadRequest = errors.New("请求参数错误")
+
+func registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/nodes", apiHandler(serveNodes))
+	mux.HandleFunc("/api/v1/pods", apiHandler(servePods))
+	mux.HandleFunc("/api/v1/namespaces/", apiHandler(servePods))
+}
+
+// 统一处理请求方法、超时及错误到HTTP状态码的转换
+func apiHandler(serve func(ctx context.Context, w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := serve(ctx, w, r); err != nil {
+			writeAPIError(w, apiStatus(err), err)
+		}
+	}
+}
+
+func apiStatus(err error) int {
+	switch {
+	case errors.Is(err, errBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, kube.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, kube.ErrNamespaceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, kube.ErrMetricsUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func querySelector(r *http.Request) Selector {
+	query := r.URL.Query()
+	return Selector{Label: query.Get("labelSelector"), Field: query.Get("fieldSelector")}
+}
+
+func serveNodes(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	sortBy := r.URL.Query().Get("sortBy")
+	if sortBy == "" {
+		sortBy = "cpu.request"
+	}
+
+	nodeInfoList, err := LoadNodeInfo(ctx, querySelector(r))
+	if err != nil {
+		return err
+	}
+	if !SortNodeInfo(nodeInfoList, sortBy) {
+		return fmt.Errorf("%w: 未知的排序选项: %s", errBadRequest, sortBy)
+	}
+	return writeNodeRecords(w, outputJSON, nodeInfoList)
+}
+
+func servePods(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	namespace := metav1.NamespaceAll
+	if r.URL.Path != "/api/v1/pods" {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] != "pods" {
+			return fmt.Errorf("%w: 未知的路径: %s", errBadRequest, r.URL.Path)
+		}
+		namespace = parts[0]
+		if err := checkNamespace(ctx, namespace); err != nil {
+			return err
+		}
+	}
+
+	query := r.URL.Query()
+	sortBy := query.Get("sortBy")
+	if sortBy == "" {
+		sortBy = "cpu.request"
+	}
+	if !podSortKeySet[sortBy] {
+		return fmt.Errorf("%w: 未知的排序选项: %s", errBadRequest, sortBy)
+	}
+	byContainer := query.Get("container") == "true"
+
+	podInfoList, err := loadSelectedPodInfo(ctx, namespace, querySelector(r))
+	var noPods noPodsError
+	if errors.As(err, &noPods) {
+		podInfoList, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	// 只排序不截断，DaemonSet的行数限制仅用于终端表格
+	return writePodRecords(w, outputJSON, sortPodInfo(podInfoList, sortBy, byContainer), byContainer)
+}
+
+// 直接查询命名空间，避免每个请求都列出全部命名空间
+func checkNamespace(ctx context.Context, namespace string) error {
+	client, err := kube.GetK8sClient(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", kube.ErrNamespaceNotFound, namespace)
+		}
+		return kube.Error(err, "查询命名空间失败")
+	}
+	return nil
+}
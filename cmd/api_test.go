@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestAPI(t *testing.T) {
+	web := newPod("default", "web-1", "node-a", newContainer("app", resourceList("100m", "200M"), nil))
+	web.Labels = map[string]string{"app": "web"}
+	batch := newPod("default", "batch-1", "node-a", newContainer("app", resourceList("200m", "100M"), nil))
+	webMetrics := newPodMetrics("default", "web-1", map[string]corev1.ResourceList{"app": resourceList("90m", "10M")})
+	webMetrics.Labels = web.Labels
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		newNode("node-a", "4", "8G"),
+		newNode("node-b", "2", "4G"),
+		web, batch,
+		newPod("kube-system", "coredns-1", "node-a", newContainer("coredns", resourceList("100m", "70M"), nil)),
+	}
+	podMetrics := []metricsv1beta1.PodMetrics{
+		webMetrics,
+		newPodMetrics("default", "batch-1", map[string]corev1.ResourceList{"app": resourceList("10m", "90M")}),
+		newPodMetrics("kube-system", "coredns-1", map[string]corev1.ResourceList{"coredns": resourceList("10m", "20M")}),
+	}
+	// DaemonSet分组排在最前，API不能按表格的方式截断
+	isController := true
+	for i := 0; i < 12; i++ {
+		agent := newPod("kube-system", fmt.Sprintf("log-agent-%02d", i), "node-a", newContainer("agent", resourceList("50m", "50M"), nil))
+		agent.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "log-agent", Controller: &isController}}
+		objects = append(objects, agent)
+		podMetrics = append(podMetrics, newPodMetrics("kube-system", agent.Name, map[string]corev1.ResourceList{"agent": resourceList("5m", "10M")}))
+	}
+	ctx := fakeContext(t,
+		objects,
+		podMetrics,
+		[]metricsv1beta1.NodeMetrics{newNodeMetrics("node-a", "1", "2G"), newNodeMetrics("node-b", "2", "1G")})
+
+	mux := http.NewServeMux()
+	registerAPI(mux)
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		url    string
+		status int
+		want   []string
+	}{
+		{url: "/api/v1/nodes?sortBy=cpu.util", status: http.StatusOK, want: []string{"node-b", "node-a"}},
+		{url: "/api/v1/nodes?sortBy=bogus", status: http.StatusBadRequest},
+		{url: "/api/v1/namespaces/default/pods?sortBy=mem.request", status: http.StatusOK, want: []string{"batch-1", "web-1"}},
+		{url: "/api/v1/namespaces/default/pods?sortBy=mem.util", status: http.StatusBadRequest},
+		{url: "/api/v1/namespaces/default/pods?labelSelector=app%3Dweb", status: http.StatusOK, want: []string{"web-1"}},
+		{url: "/api/v1/pods?labelSelector=app%3Dnone", status: http.StatusOK, want: []string{}},
+		{url: "/api/v1/namespaces/missing/pods", status: http.StatusNotFound},
+		{url: "/api/v1/namespaces/default/deployments", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rec := get(tt.url)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			var list struct {
+				Items []struct {
+					Node string `json:"node"`
+					Pod  string `json:"pod"`
+				} `json:"items"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+				t.Fatal(err)
+			}
+			if len(list.Items) != len(tt.want) {
+				t.Fatalf("got %d items, want %d: %s", len(list.Items), len(tt.want), rec.Body)
+			}
+			for i, name := range tt.want {
+				if got := list.Items[i].Node + list.Items[i].Pod; got != name && list.Items[i].Pod != name {
+					t.Errorf("item %d = %+v, want %s", i, list.Items[i], name)
+				}
+			}
+		})
+	}
+
+	for url, want := range map[string]int{"/api/v1/namespaces/kube-system/pods": 13, "/api/v1/pods": 15} {
+		var list struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(get(url).Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != want {
+			t.Errorf("%s returned %d items, want %d", url, len(list.Items), want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+/*
+表格中比例列的着色规则，列名与--fail-if的指标一致:
+  - node.cpu.request-remaining、node.mem.request-remaining  低于阈值时着色
+  - node.cpu.util、node.mem.util、node.cpu.overcommit、node.mem.overcommit  高于阈值时着色
+  - pod.cpu.request、pod.mem.request、pod.cpu.limit、pod.mem.limit  高于阈值时着色，同时作用于容器及工作负载
+达到warning显示黄色，达到critical显示红色，阈值为0表示不启用
+*/
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+
+	colorYellow = "\x1b[33m"
+	colorRed    = "\x1b[31m"
+)
+
+var (
+	colorMode      string
+	colorRuleFlags []string
+	colorEnabled   bool
+
+	// 比例越低越危险的列
+	lowerIsWorse = map[string]bool{
+		"node.cpu.request-remaining": true,
+		"node.mem.request-remaining": true,
+		"node.cpu.util":              false,
+		"node.mem.util":              false,
+		"node.cpu.overcommit":        false,
+		"node.mem.overcommit":        false,
+		"pod.cpu.request":            false,
+		"pod.mem.request":            false,
+		"pod.cpu.limit":              false,
+		"pod.mem.limit":              false,
+	}
+
+	// 默认规则，request剩余率低于20%标红沿用原有的watermark
+	colorRules = map[string]colorRule{
+		"node.cpu.request-remaining": {Warning: 30, Critical: 20},
+		"node.mem.request-remaining": {Warning: 30, Critical: 20},
+		"node.cpu.util":              {Warning: 80, Critical: 90},
+		"node.mem.util":              {Warning: 80, Critical: 90},
+		"node.cpu.overcommit":        {Warning: 150, Critical: 200},
+		"node.mem.overcommit":        {Warning: 100, Critical: 150}, // 内存不可压缩，超售即可能OOM
+		"pod.cpu.limit":              {Warning: 80, Critical: 90},
+		"pod.mem.limit":              {Warning: 80, Critical: 90},
+	}
+)
+
+type colorRule struct {
+	Warning  float64 `json:"warning"`
+	Critical float64 `json:"critical"`
+}
+
+// 返回value对应的颜色，未达到阈值时返回空字符串
+func (r colorRule) color(value float64, lower bool) string {
+	reached := func(threshold float64) bool {
+		if threshold == 0 {
+			return false
+		}
+		if lower {
+			return value < threshold
+		}
+		return value >= threshold
+	}
+	switch {
+	case reached(r.Critical):
+		return colorRed
+	case reached(r.Warning):
+		return colorYellow
+	}
+	return ""
+}
+
+// 依次应用配置文件及--color-rule中的规则，并根据--color、NO_COLOR及标准输出是否为终端决定是否着色
+func setupColor(configRules map[string]colorRule) error {
+	for column, rule := range configRules {
+		if _, ok := lowerIsWorse[column]; !ok {
+			return fmt.Errorf("配置文件colors: 未知的列 %s，可选 %s", column, strings.Join(colorColumns(), " | "))
+		}
+		colorRules[column] = rule
+	}
+	for _, expr := range colorRuleFlags {
+		column, rule, err := parseColorRule(expr)
+		if err != nil {
+			return err
+		}
+		colorRules[column] = rule
+	}
+
+	switch colorMode {
+	case colorAlways:
+		colorEnabled = true
+	case colorNever:
+		colorEnabled = false
+	case colorAuto:
+		colorEnabled = !noColorEnv() && term.IsTerminal(int(os.Stdout.Fd()))
+	default:
+		return fmt.Errorf("--color 只支持 auto | always | never: %s", colorMode)
+	}
+	return nil
+}
+
+// 按 https://no-color.org 的约定，NO_COLOR设置为非空值时才禁用颜色
+func noColorEnv() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// 解析 <列>=<warning>,<critical>，如 node.cpu.util=70,85，留空表示不启用该级别
+func parseColorRule(expr string) (string, colorRule, error) {
+	column, values, ok := strings.Cut(strings.ReplaceAll(expr, " ", ""), "=")
+	if !ok {
+		return "", colorRule{}, fmt.Errorf("--color-rule %s: 格式应为 <列>=<warning>,<critical>", expr)
+	}
+	if _, ok := lowerIsWorse[column]; !ok {
+		return "", colorRule{}, fmt.Errorf("--color-rule %s: 未知的列 %s，可选 %s", expr, column, strings.Join(colorColumns(), " | "))
+	}
+	warning, critical, _ := strings.Cut(values, ",")
+
+	var rule colorRule
+	for _, v := range []struct {
+		text   string
+		target *float64
+	}{{warning, &rule.Warning}, {critical, &rule.Critical}} {
+		if v.text == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSuffix(v.text, "%"), 64)
+		if err != nil {
+			return "", colorRule{}, fmt.Errorf("--color-rule %s: 无效的百分比 %s", expr, v.text)
+		}
+		*v.target = f
+	}
+	return column, rule, nil
+}
+
+func colorColumns() []string {
+	columns := make([]string, 0, len(lowerIsWorse))
+	for column := range lowerIsWorse {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// 按列的规则为已格式化的text着色
+func colorize(column string, value float64, text string) string {
+	if !colorEnabled {
+		return text
+	}
+	if color := colorRules[column].color(value, lowerIsWorse[column]); color != "" {
+		return color + text + colorReset
+	}
+	return text
+}
+
+// pod/容器/工作负载的比例列，无法计算(0)时输出-且不着色
+func ratioCell(column string, ratio float64) string {
+	if ratio == 0 {
+		return formatValue(ratio)
+	}
+	return colorize(column, ratio, formatValue(ratio))
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestColorRules(t *testing.T) {
+	defaults := make(map[string]colorRule, len(colorRules))
+	for column, rule := range colorRules {
+		defaults[column] = rule
+	}
+	t.Cleanup(func() {
+		colorRules, colorRuleFlags, colorMode, colorEnabled = defaults, nil, colorAuto, false
+	})
+
+	path := filepath.Join(t.TempDir(), "kubetop.yaml")
+	if err := os.WriteFile(path, []byte("colors:\n  pod.mem.limit: {warning: 50, critical: 70}\n  node.cpu.util: {critical: 95}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 命令行规则覆盖配置文件
+	colorRuleFlags = []string{"node.cpu.util=60,"}
+	colorMode = colorAlways
+	if err := setupColor(config.Colors); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		column string
+		value  float64
+		want   string
+	}{
+		{column: "pod.mem.limit", value: 40, want: "40.00%"},
+		{column: "pod.mem.limit", value: 60, want: colorYellow + "60.00%" + colorReset},
+		{column: "pod.mem.limit", value: 70, want: colorRed + "70.00%" + colorReset},
+		{column: "node.cpu.util", value: 99, want: colorYellow + "99.00%" + colorReset},
+		{column: "node.mem.request-remaining", value: 25, want: colorYellow + "25.00%" + colorReset},
+		{column: "node.mem.request-remaining", value: 10, want: colorRed + "10.00%" + colorReset},
+		{column: "pod.cpu.request", value: 500, want: "500.00%"},
+		{column: "pod.mem.limit", value: 0, want: "-"},
+	}
+	for _, tt := range tests {
+		if got := ratioCell(tt.column, tt.value); got != tt.want {
+			t.Errorf("ratioCell(%s, %v) = %q, want %q", tt.column, tt.value, got, tt.want)
+		}
+	}
+
+	// NO_COLOR或非终端时不输出转义序列
+	t.Setenv("NO_COLOR", "1")
+	colorMode = colorAuto
+	if err := setupColor(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := ratioCell("pod.mem.limit", 99); got != "99.00%" {
+		t.Errorf("ratioCell with NO_COLOR = %q", got)
+	}
+	// watch的变化高亮及ui的光标同样不输出转义序列
+	watchRows := []tableRow{{Key: "node-a", Ratios: []float64{90}, Cells: []string{"node-a", "90.00%"}}}
+	highlightChanges(watchRows, map[string][]float64{"node-a": {10}})
+	var buf strings.Builder
+	renderRows(&buf, []string{"节点名称", "比例"}, watchRows)
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("watch output with NO_COLOR contains escape sequences:\n%s", buf.String())
+	}
+	if got := cursorCells([]string{"node-a"}); got[0] != "> node-a" {
+		t.Errorf("ui cursor with NO_COLOR = %q", got[0])
+	}
+
+	// NO_COLOR为空字符串时不生效
+	t.Setenv("NO_COLOR", "")
+	if noColorEnv() {
+		t.Error("empty NO_COLOR should not disable color")
+	}
+
+	for _, expr := range []string{"pod.disk.util=1,2", "pod.mem.limit", "pod.mem.limit=a,b"} {
+		if _, _, err := parseColorRule(expr); err == nil {
+			t.Errorf("parseColorRule(%q) succeeded, want error", expr)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+/*
+配置文件，默认为 ~/.kubetop.yaml，不存在时忽略。flags中的键为命令行选项的长名称，
+commands中按子命令名称设置仅对该命令生效的选项；--profile指定的profile覆盖顶层设置，
+命令行中显式指定的选项优先于配置文件:
+
+	defaultProfile: payments        # 未指定--profile时使用
+	flags:
+	  loglevel: info
+	watermark: 20                   # node request剩余率低于该值标红，等同于colors中的critical
+	daemonsetPods: [kube-proxy, calico-node]
+	colors:
+	  pod.mem.limit: {warning: 75, critical: 90}
+	profiles:
+	  payments:
+	    flags:
+	      context: prod-admin
+	      namespace: payments
+	      container: true
+	      fail-if: ["pod.mem.limit>90"]
+	    commands:
+	      pod: {sort-by: mem.request}
+	      node: {sort-by: mem.util, selector: pool=payments}
+*/
+
+const (
+	defaultConfigName = ".kubetop.yaml"
+
+	// cobra记录MarkFlagsMutuallyExclusive分组的注解
+	mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+)
+
+var (
+	configFile string
+	profile    string
+)
+
+type kubetopConfig struct {
+	configSection
+	DefaultProfile string                   `json:"defaultProfile"`
+	Profiles       map[string]configSection `json:"profiles"`
+}
+
+// 顶层及各profile可设置的内容
+type configSection struct {
+	Flags         map[string]interface{}            `json:"flags"`
+	Commands      map[string]map[string]interface{} `json:"commands"`
+	Colors        map[string]colorRule              `json:"colors"`
+	Watermark     *float64                          `json:"watermark"`
+	DaemonSetPods []string                          `json:"daemonsetPods"`
+}
+
+// path为空时读取默认配置文件，显式指定的文件不存在时报错
+func loadConfig(path string) (*kubetopConfig, error) {
+	explicit := path != ""
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &kubetopConfig{}, nil
+		}
+		path = filepath.Join(home, defaultConfigName)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return &kubetopConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	config := &kubetopConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return config, nil
+}
+
+// 以name对应的profile覆盖顶层设置，name为空时使用defaultProfile
+func (c *kubetopConfig) resolve(name string) (configSection, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return c.configSection, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return configSection{}, fmt.Errorf("配置文件中不存在profile %s，可选: %s", name, strings.Join(names, " | "))
+	}
+
+	merged := configSection{
+		Flags:         mergeValues(c.Flags, p.Flags),
+		Commands:      make(map[string]map[string]interface{}),
+		Colors:        make(map[string]colorRule),
+		Watermark:     c.Watermark,
+		DaemonSetPods: c.DaemonSetPods,
+	}
+	for _, commands := range []map[string]map[string]interface{}{c.Commands, p.Commands} {
+		for command, values := range commands {
+			merged.Commands[command] = mergeValues(merged.Commands[command], values)
+		}
+	}
+	for _, colors := range []map[string]colorRule{c.Colors, p.Colors} {
+		for column, rule := range colors {
+			merged.Colors[column] = rule
+		}
+	}
+	if p.Watermark != nil {
+		merged.Watermark = p.Watermark
+	}
+	if p.DaemonSetPods != nil {
+		merged.DaemonSetPods = p.DaemonSetPods
+	}
+	return merged, nil
+}
+
+func mergeValues(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// watermark先作用于request剩余率列，colors中的规则优先
+func (s configSection) colorRules() map[string]colorRule {
+	rules := make(map[string]colorRule)
+	if s.Watermark != nil {
+		for _, column := range []string{"node.cpu.request-remaining", "node.mem.request-remaining"} {
+			rule := colorRules[column]
+			rule.Critical = *s.Watermark
+			rules[column] = rule
+		}
+	}
+	for column, rule := range s.Colors {
+		rules[column] = rule
+	}
+	return rules
+}
+
+// 读取配置文件并应用到cmd，在解析命令行之后、读取选项之前调用
+func applyConfig(cmd *cobra.Command) error {
+	config, err := loadConfig(configFile)
+	if err != nil {
+		return err
+	}
+	section, err := config.resolve(profile)
+	if err != nil {
+		return err
+	}
+	if err := applyConfigFlags(cmd, section); err != nil {
+		return err
+	}
+	if section.DaemonSetPods != nil {
+		daemonsetPod = section.DaemonSetPods
+	}
+	return setupColor(section.colorRules())
+}
+
+// 将配置中的选项设置到命令行未指定的flag上
+// 命令级设置优先于通用设置；当前命令没有而其他命令有的通用选项直接忽略
+func applyConfigFlags(cmd *cobra.Command, section configSection) error {
+	for command, values := range section.Commands {
+		target := findCommand(cmd.Root(), command)
+		if target == nil {
+			return fmt.Errorf("配置文件commands: 未知的命令 %s", command)
+		}
+		for name := range values {
+			if target.Flags().Lookup(name) == nil && target.InheritedFlags().Lookup(name) == nil {
+				return fmt.Errorf("配置文件commands.%s: 命令不支持选项 %s", command, name)
+			}
+		}
+	}
+
+	values := mergeValues(section.Flags, section.Commands[cmd.Name()])
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	flags := cmd.Flags()
+	for _, name := range names {
+		if name == "config" || name == "profile" {
+			return fmt.Errorf("配置文件中不能设置 --%s", name)
+		}
+		f := flags.Lookup(name)
+		if f == nil {
+			if findCommandWithFlag(cmd.Root(), name) == nil {
+				return fmt.Errorf("配置文件flags: 未知的选项 %s", name)
+			}
+			continue
+		}
+		if f.Changed || excludedByCommandLine(flags, f) {
+			continue
+		}
+
+		strs, err := configValues(values[name])
+		if err != nil {
+			return fmt.Errorf("配置文件选项 %s: %w", name, err)
+		}
+		// 切片类型的flag首次Set替换默认值，之后追加
+		for _, s := range strs {
+			if err := f.Value.Set(s); err != nil {
+				return fmt.Errorf("配置文件选项 %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// 与命令行中已指定的选项互斥时不使用配置中的值，如命令行指定-A时忽略配置中的namespace
+func excludedByCommandLine(flags *pflag.FlagSet, f *pflag.Flag) bool {
+	for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+		for _, name := range strings.Split(group, " ") {
+			if other := flags.Lookup(name); other != nil && other != f && other.Changed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// YAML中的标量或列表转换为flag可解析的字符串
+func configValues(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		var strs []string
+		for _, item := range v {
+			s, err := configValues(item)
+			if err != nil {
+				return nil, err
+			}
+			strs = append(strs, s...)
+		}
+		return strs, nil
+	case map[string]interface{}:
+		return nil, errors.New("不支持对象类型的值")
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	case nil:
+		return nil, nil
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
+
+func findCommand(root *cobra.Command, name string) *cobra.Command {
+	return walkCommands(root, func(c *cobra.Command) bool { return c.Name() == name })
+}
+
+func findCommandWithFlag(root *cobra.Command, name string) *cobra.Command {
+	return walkCommands(root, func(c *cobra.Command) bool {
+		return c.Flags().Lookup(name) != nil || c.PersistentFlags().Lookup(name) != nil
+	})
+}
+
+func walkCommands(c *cobra.Command, match func(*cobra.Command) bool) *cobra.Command {
+	if match(c) {
+		return c
+	}
+	for _, sub := range c.Commands() {
+		if found := walkCommands(sub, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const testConfig = `
+defaultProfile: payments
+flags:
+  loglevel: info
+watermark: 15
+profiles:
+  payments:
+    flags:
+      namespace: payments
+      container: true
+      fail-if: ["pod.mem.limit>90", "pod.cpu.limit>95"]
+    commands:
+      pod: {sort-by: mem.request}
+      node: {sort-by: mem.util}
+    daemonsetPods: [log-agent]
+  batch:
+    flags:
+      all-namespaces: true
+`
+
+func TestApplyConfigFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubetop.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 与root.go中相同的选项布局，使用局部变量避免影响其他测试
+	type options struct {
+		loglevel, namespace, podSort, nodeSort string
+		all, container                         bool
+		failIf                                 []string
+	}
+	newTree := func(o *options) (*cobra.Command, *cobra.Command) {
+		root := &cobra.Command{Use: "kubetop"}
+		root.PersistentFlags().StringVar(&o.loglevel, "loglevel", "warning", "")
+		pod := &cobra.Command{Use: "pod", Run: func(*cobra.Command, []string) {}}
+		pod.Flags().StringVarP(&o.namespace, "namespace", "n", "", "")
+		pod.Flags().BoolVarP(&o.all, "all-namespaces", "A", false, "")
+		pod.MarkFlagsMutuallyExclusive("namespace", "all-namespaces")
+		pod.Flags().StringVar(&o.podSort, "sort-by", "cpu.request", "")
+		pod.Flags().BoolVarP(&o.container, "container", "c", false, "")
+		pod.Flags().StringArrayVar(&o.failIf, "fail-if", nil, "")
+		node := &cobra.Command{Use: "node", Run: func(*cobra.Command, []string) {}}
+		node.Flags().StringVar(&o.nodeSort, "sort-by", "cpu.request", "")
+		root.AddCommand(pod, node)
+		return pod, node
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		node    bool
+		args    []string
+		want    options
+	}{
+		{
+			name: "default profile",
+			want: options{loglevel: "info", namespace: "payments", podSort: "mem.request", container: true, failIf: []string{"pod.mem.limit>90", "pod.cpu.limit>95"}},
+		},
+		{
+			name: "flags override profile",
+			args: []string{"--sort-by", "cpu.limit", "--fail-if", "pod.mem.limit>50", "-n", "web"},
+			want: options{loglevel: "info", namespace: "web", podSort: "cpu.limit", container: true, failIf: []string{"pod.mem.limit>50"}},
+		},
+		{
+			name: "command line -A skips configured namespace",
+			args: []string{"-A"},
+			want: options{loglevel: "info", all: true, podSort: "mem.request", container: true, failIf: []string{"pod.mem.limit>90", "pod.cpu.limit>95"}},
+		},
+		{
+			name: "command specific flags",
+			node: true,
+			want: options{loglevel: "info", podSort: "cpu.request", nodeSort: "mem.util"},
+		},
+		{
+			name:    "named profile",
+			profile: "batch",
+			want:    options{loglevel: "info", all: true, podSort: "cpu.request"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := options{}
+			pod, node := newTree(&got)
+			cmd := pod
+			if tt.node {
+				cmd = node
+			}
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			section, err := config.resolve(tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := applyConfigFlags(cmd, section); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want.nodeSort == "" {
+				tt.want.nodeSort = "cpu.request"
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	section, _ := config.resolve("")
+	if !reflect.DeepEqual(section.DaemonSetPods, []string{"log-agent"}) {
+		t.Errorf("daemonsetPods = %v", section.DaemonSetPods)
+	}
+	if rule := section.colorRules()["node.cpu.request-remaining"]; rule.Critical != 15 {
+		t.Errorf("watermark not applied: %+v", rule)
+	}
+	if _, err := config.resolve("missing"); err == nil || !strings.Contains(err.Error(), "batch | payments") {
+		t.Errorf("resolve(missing) = %v", err)
+	}
+}
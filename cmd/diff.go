@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"metrics.k8s.io/kube"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffThreshold  float64
+	nodeDiffHeader = []string{"节点名称", "cpu|request剩余率", "变化", "cpu|实际使用率", "内存|request剩余率", "变化", "内存|实际使用率", "状态"}
+	podDiffHeader  = []string{"命名空间", "pod名称", "变化", "cpu request|limit|usage", "内存 request|limit|usage"}
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD NEW",
+	Short: "Compare two snapshots to show capacity drift",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		older, err := loadSnapshotState(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		newer, err := loadSnapshotState(cmd.Context(), args[1])
+		if err != nil {
+			return err
+		}
+		PrintDiff(os.Stdout, older, newer)
+		return nil
+	},
+	Args: cobra.ExactArgs(2),
+}
+
+// 快照中与对比相关的数据，pod以encode(ns,pod)为键
+type snapshotState struct {
+	Path      string
+	Timestamp time.Time
+	Nodes     []nodeInfo
+	Pods      map[string]*PodInfo
+}
+
+// 以快照为数据源，复用node/pod命令的采集逻辑
+func loadSnapshotState(ctx context.Context, path string) (*snapshotState, error) {
+	snapshot, err := kube.LoadSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	clients, err := snapshot.Clients()
+	if err != nil {
+		return nil, err
+	}
+	ctx = kube.WithClients(ctx, clients)
+
+	nodes, err := LoadNodeInfo(ctx, nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	// 命名空间或选择器在某个快照中没有匹配的pod时按空集合对比，即全部新增或全部移除
+	resources, err := LoadK8sResource(ctx, namespace, podSelector)
+	var noPods noPodsError
+	if errors.As(err, &noPods) {
+		resources, err = map[string]PodResource{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := LoadK8sMetrics(ctx, namespace, podSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// 没有metrics的pod同样参与对比，用量按0处理
+	pods := make(map[string]*PodInfo, len(resources))
+	for key, resource := range resources {
+		pods[key] = &PodInfo{PodResource: resource, PodMetrics: metrics[key]}
+	}
+	return &snapshotState{Path: path, Timestamp: snapshot.Timestamp.Time, Nodes: nodes, Pods: pods}, nil
+}
+
+// 单个节点在两次快照间的变化，Old或New为nil表示节点新增或移除
+type nodeDiff struct {
+	NodeName string
+	Old, New *nodeInfo
+}
+
+// request剩余率下降的百分点，取cpu与内存中的较大值
+func (d nodeDiff) headroomDrop() float64 {
+	if d.Old == nil || d.New == nil {
+		return 0
+	}
+	cpu := d.Old.CPUPercentage - d.New.CPUPercentage
+	mem := d.Old.MemoryPercentage - d.New.MemoryPercentage
+	if cpu > mem {
+		return cpu
+	}
+	return mem
+}
+
+type podChange string
+
+const (
+	podAdded   podChange = "新增"
+	podRemoved podChange = "移除"
+	podResized podChange = "调整"
+)
+
+var podChangeOrder = map[podChange]int{podAdded: 0, podRemoved: 1, podResized: 2}
+
+// 新增、移除或request/limit发生变化的pod
+type podDiff struct {
+	Namespace string
+	PodName   string
+	Change    podChange
+	Old, New  podTotals
+}
+
+// 按节点名称对齐两次快照的节点
+func DiffNodes(older, newer []nodeInfo) []nodeDiff {
+	diffs := make(map[string]*nodeDiff)
+	for i := range older {
+		diffs[older[i].NodeName] = &nodeDiff{NodeName: older[i].NodeName, Old: &older[i]}
+	}
+	for i := range newer {
+		d, ok := diffs[newer[i].NodeName]
+		if !ok {
+			d = &nodeDiff{NodeName: newer[i].NodeName}
+			diffs[newer[i].NodeName] = d
+		}
+		d.New = &newer[i]
+	}
+
+	diffList := make([]nodeDiff, 0, len(diffs))
+	for _, d := range diffs {
+		diffList = append(diffList, *d)
+	}
+	// 余量下降最多的节点排在最前
+	sort.Slice(diffList, func(i, j int) bool {
+		if diffList[i].headroomDrop() != diffList[j].headroomDrop() {
+			return diffList[i].headroomDrop() > diffList[j].headroomDrop()
+		}
+		return diffList[i].NodeName < diffList[j].NodeName
+	})
+	return diffList
+}
+
+// 按命名空间/名称对齐两次快照的pod，只返回有变化的pod
+func DiffPods(older, newer map[string]*PodInfo) []podDiff {
+	var diffs []podDiff
+	for key, oldPod := range older {
+		newPod, ok := newer[key]
+		switch {
+		case !ok:
+			diffs = append(diffs, podDiff{Namespace: oldPod.PodResource.Namespace, PodName: oldPod.PodResource.PodName, Change: podRemoved, Old: oldPod.totals()})
+		case resized(oldPod.totals(), newPod.totals()):
+			diffs = append(diffs, podDiff{Namespace: oldPod.PodResource.Namespace, PodName: oldPod.PodResource.PodName, Change: podResized, Old: oldPod.totals(), New: newPod.totals()})
+		}
+	}
+	for key, newPod := range newer {
+		if _, ok := older[key]; !ok {
+			diffs = append(diffs, podDiff{Namespace: newPod.PodResource.Namespace, PodName: newPod.PodResource.PodName, Change: podAdded, New: newPod.totals()})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Change != diffs[j].Change {
+			return podChangeOrder[diffs[i].Change] < podChangeOrder[diffs[j].Change]
+		}
+		if diffs[i].Namespace != diffs[j].Namespace {
+			return diffs[i].Namespace < diffs[j].Namespace
+		}
+		return diffs[i].PodName < diffs[j].PodName
+	})
+	return diffs
+}
+
+func resized(older, newer podTotals) bool {
+	return older.CPURequests != newer.CPURequests || older.CPULimits != newer.CPULimits ||
+		older.MemRequests != newer.MemRequests || older.MemLimits != newer.MemLimits
+}
+
+func PrintDiff(w io.Writer, older, newer *snapshotState) {
+	fmt.Fprintf(w, "%s (%s) -> %s (%s)\n\n", older.Path, older.Timestamp.Format(time.RFC3339), newer.Path, newer.Timestamp.Format(time.RFC3339))
+
+	nodeDiffs := DiffNodes(older.Nodes, newer.Nodes)
+	var dropped int
+	nodeRows := make([]tableRow, 0, len(nodeDiffs))
+	for _, d := range nodeDiffs {
+		var status string
+		switch {
+		case d.Old == nil:
+			status = "新增"
+		case d.New == nil:
+			status = "移除"
+		case d.headroomDrop() >= diffThreshold:
+			status = fmt.Sprintf("余量下降%.2f个百分点", d.headroomDrop())
+			dropped++
+		}
+		old, cur := d.Old, d.New
+		if old == nil {
+			old = &nodeInfo{}
+		}
+		if cur == nil {
+			cur = &nodeInfo{}
+		}
+		nodeRows = append(nodeRows, tableRow{Cells: []string{
+			d.NodeName,
+			formatChange(old.CPUPercentage, cur.CPUPercentage),
+			fmt.Sprintf("%+.2f", cur.CPUPercentage-old.CPUPercentage),
+			formatUtilizationChange(d.Old, d.New, func(n *nodeInfo) float64 { return n.NodeCPUUtilization }),
+			formatChange(old.MemoryPercentage, cur.MemoryPercentage),
+			fmt.Sprintf("%+.2f", cur.MemoryPercentage-old.MemoryPercentage),
+			formatUtilizationChange(d.Old, d.New, func(n *nodeInfo) float64 { return n.NodeMemUtilization }),
+			status,
+		}})
+	}
+	fmt.Fprintf(w, "节点: %d个，request剩余率下降超过%.0f个百分点的有%d个\n", len(nodeDiffs), diffThreshold, dropped)
+	renderRows(w, nodeDiffHeader, nodeRows)
+
+	podDiffs := DiffPods(older.Pods, newer.Pods)
+	counts := make(map[podChange]int)
+	podRows := make([]tableRow, 0, len(podDiffs))
+	for _, d := range podDiffs {
+		counts[d.Change]++
+		cpu := formatResourceUsage(d.Old.CPURequests, d.Old.CPULimits, d.Old.CPUUsage, "CPU") + " -> " + formatResourceUsage(d.New.CPURequests, d.New.CPULimits, d.New.CPUUsage, "CPU")
+		mem := formatResourceUsage(d.Old.MemRequests, d.Old.MemLimits, d.Old.MemUsage, "Memory") + " -> " + formatResourceUsage(d.New.MemRequests, d.New.MemLimits, d.New.MemUsage, "Memory")
+		switch d.Change {
+		case podAdded:
+			cpu = formatResourceUsage(d.New.CPURequests, d.New.CPULimits, d.New.CPUUsage, "CPU")
+			mem = formatResourceUsage(d.New.MemRequests, d.New.MemLimits, d.New.MemUsage, "Memory")
+		case podRemoved:
+			cpu = formatResourceUsage(d.Old.CPURequests, d.Old.CPULimits, d.Old.CPUUsage, "CPU")
+			mem = formatResourceUsage(d.Old.MemRequests, d.Old.MemLimits, d.Old.MemUsage, "Memory")
+		}
+		podRows = append(podRows, tableRow{Cells: []string{d.Namespace, d.PodName, string(d.Change), cpu, mem}})
+	}
+	fmt.Fprintf(w, "\npod: 新增%d个，移除%d个，调整request/limit%d个\n", counts[podAdded], counts[podRemoved], counts[podResized])
+	renderRows(w, podDiffHeader, podRows)
+}
+
+func formatChange(older, newer float64) string {
+	return fmt.Sprintf("%.2f%% -> %.2f%%", older, newer)
+}
+
+// 节点不存在或没有metrics时实际使用率未知，输出-
+func formatUtilizationChange(older, newer *nodeInfo, utilization func(*nodeInfo) float64) string {
+	text := func(n *nodeInfo) string {
+		if n == nil || !n.MetricsAvailable {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f%%", utilization(n))
+	}
+	return text(older) + " -> " + text(newer)
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"metrics.k8s.io/kube"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func saveTestSnapshot(t *testing.T, name string, objects []runtime.Object, podMetrics []metricsv1beta1.PodMetrics, nodeMetrics []metricsv1beta1.NodeMetrics) string {
+	t.Helper()
+
+	snapshot, err := kube.CaptureSnapshot(fakeContext(t, objects, podMetrics, nodeMetrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := snapshot.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiffNodes(t *testing.T) {
+	older := []nodeInfo{
+		{NodeName: "node-a", CPUPercentage: 60, MemoryPercentage: 50},
+		{NodeName: "node-b", CPUPercentage: 40, MemoryPercentage: 40},
+		{NodeName: "node-old", CPUPercentage: 90, MemoryPercentage: 90},
+	}
+	newer := []nodeInfo{
+		{NodeName: "node-a", CPUPercentage: 55, MemoryPercentage: 20},
+		{NodeName: "node-b", CPUPercentage: 45, MemoryPercentage: 40},
+		{NodeName: "node-new", CPUPercentage: 100, MemoryPercentage: 100},
+	}
+
+	diffs := DiffNodes(older, newer)
+	want := []struct {
+		name string
+		drop float64
+	}{
+		{"node-a", 30}, // 内存剩余率下降30个百分点
+		{"node-b", 0},  // cpu剩余率上升，取较大值
+		{"node-new", 0},
+		{"node-old", 0},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("got %d diffs, want %d", len(diffs), len(want))
+	}
+	for i, w := range want {
+		if diffs[i].NodeName != w.name || !almostEqual(diffs[i].headroomDrop(), w.drop) {
+			t.Errorf("position %d = %s (%.2f), want %s (%.2f)", i, diffs[i].NodeName, diffs[i].headroomDrop(), w.name, w.drop)
+		}
+	}
+	if diffs[2].Old != nil || diffs[3].New != nil {
+		t.Error("added/removed nodes should have nil Old/New")
+	}
+}
+
+func TestDiffPods(t *testing.T) {
+	older := map[string]*PodInfo{
+		"web":     newTestPodInfo("default", "web-1", "node-a", "app", 100, 50),
+		"removed": newTestPodInfo("default", "old-1", "node-a", "app", 100, 50),
+		"same":    newTestPodInfo("default", "same-1", "node-a", "app", 100, 50),
+	}
+	newer := map[string]*PodInfo{
+		"web":   newTestPodInfo("default", "web-1", "node-a", "app", 200, 50),
+		"added": newTestPodInfo("default", "new-1", "node-a", "app", 100, 50),
+		// 用量变化不算调整
+		"same": newTestPodInfo("default", "same-1", "node-a", "app", 100, 90),
+	}
+
+	diffs := DiffPods(older, newer)
+	want := []struct {
+		pod    string
+		change podChange
+	}{
+		{"new-1", podAdded},
+		{"old-1", podRemoved},
+		{"web-1", podResized},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("got %d diffs, want %d: %+v", len(diffs), len(want), diffs)
+	}
+	for i, w := range want {
+		if diffs[i].PodName != w.pod || diffs[i].Change != w.change {
+			t.Errorf("position %d = %s %s, want %s %s", i, diffs[i].PodName, diffs[i].Change, w.pod, w.change)
+		}
+	}
+	if diffs[2].Old.CPURequests != 100 || diffs[2].New.CPURequests != 200 {
+		t.Errorf("resized = %+v -> %+v", diffs[2].Old, diffs[2].New)
+	}
+}
+
+func TestLoadSnapshotStateEmptyAndUnknown(t *testing.T) {
+	pod := newPod("batch", "job-1", "node-a", newContainer("job", resourceList("1", "1G"), nil))
+	olderPath := saveTestSnapshot(t, "older.json",
+		[]runtime.Object{newNode("node-a", "4", "8G"), pod},
+		[]metricsv1beta1.PodMetrics{newPodMetrics("batch", "job-1", map[string]corev1.ResourceList{"job": resourceList("500m", "500M")})},
+		[]metricsv1beta1.NodeMetrics{newNodeMetrics("node-a", "1", "2G")})
+	// 新快照中命名空间下没有pod，节点也没有metrics
+	newerPath := saveTestSnapshot(t, "newer.json", []runtime.Object{newNode("node-a", "4", "8G")}, nil, nil)
+
+	oldNamespace := namespace
+	namespace = "batch"
+	defer func() { namespace = oldNamespace }()
+
+	older, err := loadSnapshotState(context.Background(), olderPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := loadSnapshotState(context.Background(), newerPath)
+	if err != nil {
+		t.Fatalf("snapshot without matching pods should load as empty: %v", err)
+	}
+	if len(newer.Pods) != 0 || len(newer.Nodes) != 1 {
+		t.Fatalf("newer = %d pods, %d nodes, want 0 pods, 1 node", len(newer.Pods), len(newer.Nodes))
+	}
+
+	pods := DiffPods(older.Pods, newer.Pods)
+	if len(pods) != 1 || pods[0].Change != podRemoved {
+		t.Errorf("pod diffs = %+v, want job-1 removed", pods)
+	}
+	nodes := DiffNodes(older.Nodes, newer.Nodes)
+	if len(nodes) != 1 || nodes[0].Old == nil || nodes[0].New == nil {
+		t.Fatalf("node diffs = %+v, want node-a in both snapshots", nodes)
+	}
+
+	var buf bytes.Buffer
+	PrintDiff(&buf, older, newer)
+	if !strings.Contains(buf.String(), "25.00% -> -") {
+		t.Errorf("unknown usage should render as -:\n%s", buf.String())
+	}
+}
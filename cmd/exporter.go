@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prometheus文本格式(0.0.4)的单条样本，labels按name,value成对排列
+type promSample struct {
+	labels []string
+	value  float64
+}
+
+type promFamily struct {
+	name, help, typ string
+	samples         []promSample
+}
+
+// 输出节点及容器的比例指标，比例取值0-1(用量可超过1)，分母为0的比例不输出
+func writeExposition(w io.Writer, nodes []nodeInfo, pods []*PodInfo, updated time.Time, errors int) {
+	nodeRemainingCPU := promFamily{name: "kubetop_node_cpu_request_remaining_ratio", help: "1 - sum of pod cpu requests / node allocatable cpu.", typ: "gauge"}
+	nodeRemainingMem := promFamily{name: "kubetop_node_memory_request_remaining_ratio", help: "1 - sum of pod memory requests / node allocatable memory.", typ: "gauge"}
+	nodeUtilCPU := promFamily{name: "kubetop_node_cpu_utilization_ratio", help: "Node cpu usage / node capacity.", typ: "gauge"}
+	nodeUtilMem := promFamily{name: "kubetop_node_memory_utilization_ratio", help: "Node memory usage / node capacity.", typ: "gauge"}
+	for _, node := range nodes {
+		labels := []string{"node", node.NodeName}
+		nodeRemainingCPU.add(labels, node.CPUPercentage/100)
+		nodeRemainingMem.add(labels, node.MemoryPercentage/100)
+		// 没有metrics的节点不输出使用率
+		if node.MetricsAvailable {
+			nodeUtilCPU.add(labels, node.NodeCPUUtilization/100)
+			nodeUtilMem.add(labels, node.NodeMemUtilization/100)
+		}
+	}
+
+	cpuRequest := promFamily{name: "kubetop_container_cpu_usage_to_request_ratio", help: "Container cpu usage / cpu request.", typ: "gauge"}
+	cpuLimit := promFamily{name: "kubetop_container_cpu_usage_to_limit_ratio", help: "Container cpu usage / cpu limit.", typ: "gauge"}
+	memRequest := promFamily{name: "kubetop_container_memory_usage_to_request_ratio", help: "Container memory usage / memory request.", typ: "gauge"}
+	memLimit := promFamily{name: "kubetop_container_memory_usage_to_limit_ratio", help: "Container memory usage / memory limit.", typ: "gauge"}
+	for _, podInfo := range pods {
+		for name, containerResource := range podInfo.PodResource.Containers {
+			containerMetric, ok := podInfo.PodMetrics.Containers[name]
+			if !ok {
+				continue
+			}
+			labels := []string{"namespace", podInfo.PodResource.Namespace, "pod", podInfo.PodResource.PodName, "container", name, "node", podInfo.NodeName}
+			cpuRequest.addRatio(labels, containerMetric.CPUUsage, containerResource.CPURequests)
+			cpuLimit.addRatio(labels, containerMetric.CPUUsage, containerResource.CPULimits)
+			memRequest.addRatio(labels, containerMetric.MemUsage, containerResource.MemRequest)
+			memLimit.addRatio(labels, containerMetric.MemUsage, containerResource.MemLimits)
+		}
+	}
+
+	collected := promFamily{name: "kubetop_collect_timestamp_seconds", help: "Unix time of the last successful collection.", typ: "gauge"}
+	collected.add(nil, float64(updated.Unix()))
+	collectErrors := promFamily{name: "kubetop_collect_errors_total", help: "Number of failed collections.", typ: "counter"}
+	collectErrors.add(nil, float64(errors))
+
+	for _, family := range []promFamily{nodeRemainingCPU, nodeRemainingMem, nodeUtilCPU, nodeUtilMem, cpuRequest, cpuLimit, memRequest, memLimit, collected, collectErrors} {
+		family.write(w)
+	}
+}
+
+func (f *promFamily) add(labels []string, value float64) {
+	f.samples = append(f.samples, promSample{labels: labels, value: value})
+}
+
+func (f *promFamily) addRatio(labels []string, usage, requestOrLimit int64) {
+	if requestOrLimit == 0 {
+		return
+	}
+	f.add(labels, float64(usage)/float64(requestOrLimit))
+}
+
+func (f *promFamily) write(w io.Writer) {
+	lines := make([]string, 0, len(f.samples))
+	for _, s := range f.samples {
+		lines = append(lines, f.name+formatLabels(s.labels)+" "+strconv.FormatFloat(s.value, 'g', -1, 64))
+	}
+	// 输出顺序稳定，便于对比
+	sort.Strings(lines)
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+labelEscaper.Replace(labels[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
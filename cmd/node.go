@@ -3,10 +3,11 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 
 	"metrics.k8s.io/kube"
 
@@ -16,57 +17,214 @@ import (
 )
 
 var (
-	// 互斥锁用于保证并发安全
-	mutex sync.Mutex
-	wg    sync.WaitGroup
+	nodeHeader     = []string{"节点名称", "状态", "污点", "cpu|request剩余率", "cpu|实际使用率", "cpu|limit超售率", "内存|request剩余率", "内存|实际使用率", "内存|limit超售率"}
+	nodeWideHeader = []string{"节点名称", "状态", "污点", "cpu(核) 可分配|容量", "cpu(核) request|limit|使用|剩余", "cpu|request剩余率", "cpu|实际使用率", "cpu|limit超售率",
+		"内存(GiB) 可分配|容量", "内存(GiB) request|limit|使用|剩余", "内存|request剩余率", "内存|实际使用率", "内存|limit超售率", "pod数|上限"}
+	pendingHeader = []string{"命名空间", "pod名称", "cpu request", "内存 request"}
 
-	watermark  float64 = 20.0 // 内存及CPU阈值
-	nodeHeader         = []string{"节点名称", "cpu|request剩余率", "cpu|实际使用率", "内存|request剩余率", "内存|实际使用率"}
+	excludeUnschedulable bool
+
+	// 节点状态中展示的异常状况，取值为True时输出
+	nodePressureConditions = []v1.NodeConditionType{v1.NodeMemoryPressure, v1.NodeDiskPressure, v1.NodePIDPressure, v1.NodeNetworkUnavailable}
 )
 
+// 已结束(Succeeded/Failed)的pod不再占用节点资源，在服务端过滤以减少传输
+const activePodsFieldSelector = "status.phase!=Succeeded,status.phase!=Failed"
+
+// 尚未调度到节点的pod及其request
+type pendingPod struct {
+	Namespace   string
+	PodName     string
+	CPURequests int64
+	MemRequests int64
+}
+
 var nodeCmd = &cobra.Command{
 	Use:   "node",
 	Short: "print the CPU/Mem remaining of nodes",
-	Run: func(cmd *cobra.Command, args []string) {
-		GetNodeResource(rootCmd.Context())
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(outputFormat, outputWide); err != nil {
+			return err
+		}
+		if err := validateWatch(); err != nil {
+			return err
+		}
+		thresholds, err := parseFailIf("node")
+		if err != nil {
+			return err
+		}
+		groupKey, err := parseNodeGroupBy(nodeGroupBy)
+		if err != nil {
+			return err
+		}
+		if watchMode {
+			return watchTable(cmd.Context(), nodeView(groupKey))
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), requestTimeout)
+		defer cancel()
+		return GetNodeResource(ctx, groupKey, thresholds)
 	},
 	Args:    cobra.NoArgs,
 	Aliases: []string{"nodes", "no"},
 }
 
 // 定义一个结构体用于存储节点的资源信息
+// CPU单位为毫核，内存单位为字节，百分比取值0-100
 type nodeInfo struct {
 	NodeName           string
-	CPUTotal           int64
-	CPUAllocated       int64
+	CPUTotal           int64 // 节点可分配CPU
+	CPUAllocated       int64 // 节点上pod的CPU request总和
 	CPURemaining       int64
-	CPUPercentage      float64
-	NodeCPUUtilization string // 节点实际CPU使用率
+	CPUPercentage      float64 // CPU request剩余率
+	CPUUsage           int64
+	NodeCPUUtilization float64 // 节点实际CPU使用率
 	MemoryTotal        int64
 	MemoryAllocated    int64
 	MemoryRemaining    int64
-	MemoryPercentage   float64 // 节点实际内存使用率
-	NodeMemUtilization string
+	MemoryPercentage   float64
+	MemoryUsage        int64
+	NodeMemUtilization float64 // 节点实际内存使用率
+	CPUCapacity        int64
+	CPULimits          int64 // 节点上pod的CPU limit总和
+	MemoryCapacity     int64
+	MemoryLimits       int64
+	Pods               int64   // 节点上未结束的pod数
+	PodCapacity        int64   // 节点可分配的pod数
+	CPUOvercommit      float64 // CPU limit总和/可分配，超过100表示全部突发时超售
+	MemoryOvercommit   float64
+	Labels             map[string]string
+	Status             string   // 与kubectl get node一致，如 Ready,SchedulingDisabled
+	Taints             []string // key=value:effect
+	Schedulable        bool     // Ready、未被cordon且没有NoSchedule/NoExecute污点
+	MetricsAvailable   bool     // metrics-server中有该节点的数据，NotReady的节点通常没有，此时用量未知
 }
 
 // 定义一个结构体用于存储节点的资源信息
 type nodeResource struct {
 	cpuRequest    int64 // 节点总cpu请求量
 	memoryRequest int64 // 节点总内存请求量
+	cpuLimit      int64
+	memoryLimit   int64
+	pods          int64
 }
 
 // 定义一个结构体用于存储节点的实际使用指标信息
 type nodeMetrics struct {
-	cpuPercentage string // 节点实际CPU用量
-	memPercentage string // 节点实际内存用量
+	cpuUsage      int64
+	memUsage      int64
+	cpuPercentage float64 // 节点实际CPU用量
+	memPercentage float64 // 节点实际内存用量
+}
+
+// 输出节点信息，指定阈值时在输出后返回未通过的节点
+// groupKey不为空时按该节点标签分组输出小计，结构化输出只包含各组的小计
+func GetNodeResource(ctx context.Context, groupKey string, thresholds []threshold) error {
+	nodeInfoList, pending, err := loadSortedNodeInfo(ctx)
+	if err != nil {
+		return err
+	}
+	var excluded []string
+	if excludeUnschedulable {
+		nodeInfoList, excluded = filterSchedulable(nodeInfoList)
+	}
+
+	switch {
+	case groupKey != "" && isStructuredOutput(outputFormat):
+		err = writeNodeRecords(os.Stdout, outputFormat, groupSubtotals(groupNodes(nodeInfoList, groupKey, nodeSortBy)))
+	case isStructuredOutput(outputFormat):
+		err = writeNodeRecords(os.Stdout, outputFormat, nodeInfoList)
+	case groupKey != "":
+		header, rows := nodeGroupTableRows(groupNodes(nodeInfoList, groupKey, nodeSortBy))
+		renderRows(os.Stdout, header, rows)
+		printExcludedNodes(os.Stdout, excluded)
+		PrintPendingDemand(os.Stdout, pending)
+	default:
+		PrintNodeInfo(nodeInfoList)
+		printExcludedNodes(os.Stdout, excluded)
+		PrintPendingDemand(os.Stdout, pending)
+	}
+	if err != nil {
+		return err
+	}
+	return thresholdError(nodeViolations(thresholds, nodeInfoList))
+}
+
+func loadSortedNodeInfo(ctx context.Context) ([]nodeInfo, []pendingPod, error) {
+	nodeInfoList, pending, err := loadNodeState(ctx, nodeSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 根据用户传入的选项进行排序
+	if !SortNodeInfo(nodeInfoList, nodeSortBy) {
+		return nil, nil, fmt.Errorf("未知的排序选项: %s", nodeSortBy)
+	}
+	return nodeInfoList, pending, nil
+}
+
+// watch模式下周期刷新的节点视图
+func nodeView(groupKey string) tableView {
+	return func(ctx context.Context) ([]string, []tableRow, error) {
+		nodeInfoList, _, err := loadSortedNodeInfo(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if excludeUnschedulable {
+			nodeInfoList, _ = filterSchedulable(nodeInfoList)
+		}
+		if groupKey != "" {
+			header, rows := nodeGroupTableRows(groupNodes(nodeInfoList, groupKey, nodeSortBy))
+			return header, rows, nil
+		}
+		header, rows := nodeTableRows(nodeInfoList)
+		return header, rows, nil
+	}
+}
+
+// 汇总各节点的request剩余率及实际使用率
+// 选择器只作用于节点，节点上的pod仍全部计入request
+func LoadNodeInfo(ctx context.Context, selector Selector) ([]nodeInfo, error) {
+	nodeInfoList, _, err := loadNodeState(ctx, selector)
+	return nodeInfoList, err
 }
 
-func GetNodeResource(ctx context.Context) {
-	nodes, err := kube.GetK8sClient().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
-	kube.Error(err, "列出节点失败")
+// 与LoadNodeInfo相同，另外返回尚未调度的pod(按命名空间、名称排序)
+func loadNodeState(ctx context.Context, selector Selector) ([]nodeInfo, []pendingPod, error) {
+	client, err := kube.GetK8sClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, selector.ListOptions())
+	if err != nil {
+		return nil, nil, kube.Error(err, "列出节点失败")
+	}
 
-	pods, err := kube.GetK8sClient().CoreV1().Pods("").List(ctx, metav1.ListOptions{})
-	kube.Error(err, "列出所有Pod失败")
+	pods, err := client.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: activePodsFieldSelector})
+	if err != nil {
+		return nil, nil, kube.Error(err, "列出所有Pod失败")
+	}
+
+	// 与调度器一致只统计未结束的pod；尚未分配节点的pod计入待调度需求
+	var scheduled []v1.Pod
+	var pending []pendingPod
+	for _, pod := range pods.Items {
+		switch {
+		case pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed:
+		case pod.Spec.NodeName == "":
+			cpu, memory := calculatePodRequests(pod)
+			pending = append(pending, pendingPod{Namespace: pod.Namespace, PodName: pod.Name, CPURequests: cpu, MemRequests: memory})
+		default:
+			scheduled = append(scheduled, pod)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Namespace != pending[j].Namespace {
+			return pending[i].Namespace < pending[j].Namespace
+		}
+		return pending[i].PodName < pending[j].PodName
+	})
 
 	// 创建一个用于存储节点资源信息的映射，键为节点名称，值为节点资源信息
 	nodeResources := make(map[string]nodeResource, 0)
@@ -77,41 +235,36 @@ func GetNodeResource(ctx context.Context) {
 		nodeMap[node.Name] = node
 	}
 
-	// 使用 WaitGroup 来同步并发任务
-	wg.Add(len(pods.Items))
-	// 遍历所有 Pod，对每个 Pod 启动一个 Goroutine 并进行资源累加
-	for _, pod := range pods.Items {
-		go func(pod v1.Pod) {
-			// 使用互斥锁保证并发更新 nodeResources 的安全性
-			mutex.Lock()
-			defer mutex.Unlock()
-			defer wg.Done()
-
-			nodeName := pod.Spec.NodeName
-			cpuRequest, memoryRequest := calculatePodRequests(pod)
+	// 遍历所有 Pod，按节点累加资源
+	for _, pod := range scheduled {
+		nodeName := pod.Spec.NodeName
+		resources := newPodResource(pod).effectiveResources()
 
-			// 更新节点的资源信息
-			nodeResource := nodeResources[nodeName]
-			nodeResource.cpuRequest += cpuRequest
-			nodeResource.memoryRequest += memoryRequest
-			nodeResources[nodeName] = nodeResource
-		}(pod)
+		// 更新节点的资源信息
+		nodeResource := nodeResources[nodeName]
+		nodeResource.cpuRequest += resources.CPURequests
+		nodeResource.memoryRequest += resources.MemRequest
+		nodeResource.cpuLimit += resources.CPULimits
+		nodeResource.memoryLimit += resources.MemLimits
+		nodeResource.pods++
+		nodeResources[nodeName] = nodeResource
 	}
 
-	// 等待所有 Goroutine 完成
-	wg.Wait()
-
-	nodesMetrics := getNodeUtilization(ctx, nodeMap)
+	nodesMetrics, err := getNodeUtilization(ctx, nodeMap, selector)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// 遍历所有节点，计算节点的总资源和剩余资源，并输出结果
 	var nodeInfoList []nodeInfo
 	for _, node := range nodes.Items {
 		nodeName := node.Name
 		nodeResource := nodeResources[nodeName]
-		nodeMetrics := nodesMetrics[nodeName]
+		nodeMetrics, ok := nodesMetrics[nodeName]
 
 		// 获取节点的可分配资源信息
 		cpuTotal, memoryTotal := getNodeAllocatable(node)
+		cpuCapacity, memoryCapacity := getNodeCapacity(node)
 
 		// 计算节点的剩余资源
 		cpuRemaining := calculateRemaining(cpuTotal, nodeResource.cpuRequest)
@@ -121,42 +274,55 @@ func GetNodeResource(ctx context.Context) {
 		cpuPercentage := calculateRemaingPercentage(cpuRemaining, cpuTotal)
 		memoryPercentage := calculateRemaingPercentage(memoryRemaining, memoryTotal)
 
-		if nodeMetrics.cpuPercentage == "" || nodeMetrics.memPercentage == ""  {
-			kube.Info((fmt.Errorf("节点%s资源异常",nodeName)),"跳过该节点,")
-			continue
+		if !ok {
+			kube.Info(fmt.Errorf("节点%s没有metrics数据", nodeName), "实际用量未知,")
 		}
+		status, schedulable := getNodeStatus(node)
 
 		// 添加节点信息到 nodeInfoList 切片中
 		nodeInfoList = append(nodeInfoList, nodeInfo{
-			NodeName: nodeName,
-			// CPUTotal:           cpuTotal,
-			// CPUAllocated:       nodeResource.cpuRequest,
-			// CPURemaining:       cpuRemaining,
+			NodeName:           nodeName,
+			CPUTotal:           cpuTotal,
+			CPUAllocated:       nodeResource.cpuRequest,
+			CPURemaining:       cpuRemaining,
 			CPUPercentage:      cpuPercentage,
+			CPUUsage:           nodeMetrics.cpuUsage,
 			NodeCPUUtilization: nodeMetrics.cpuPercentage,
-			// MemoryTotal:        memoryTotal,
-			// MemoryAllocated:    nodeResource.memoryRequest,
-			// MemoryRemaining:    memoryRemaining,
+			MemoryTotal:        memoryTotal,
+			MemoryAllocated:    nodeResource.memoryRequest,
+			MemoryRemaining:    memoryRemaining,
 			MemoryPercentage:   memoryPercentage,
+			MemoryUsage:        nodeMetrics.memUsage,
 			NodeMemUtilization: nodeMetrics.memPercentage,
+			CPUCapacity:        cpuCapacity,
+			CPULimits:          nodeResource.cpuLimit,
+			MemoryCapacity:     memoryCapacity,
+			MemoryLimits:       nodeResource.memoryLimit,
+			Pods:               nodeResource.pods,
+			PodCapacity:        node.Status.Allocatable.Pods().Value(),
+			CPUOvercommit:      calculateRatio(nodeResource.cpuLimit, cpuTotal),
+			MemoryOvercommit:   calculateRatio(nodeResource.memoryLimit, memoryTotal),
+			Labels:             node.Labels,
+			Status:             status,
+			Taints:             nodeTaints(node),
+			Schedulable:        schedulable,
+			MetricsAvailable:   ok,
 		})
 	}
 
-	// 根据用户传入的选项进行排序
-	switch nodeSortBy {
+	return nodeInfoList, pending, nil
+}
+
+// 按排序规则对节点进行排序，未知的排序规则返回false
+func SortNodeInfo(nodeInfoList []nodeInfo, sortBy string) bool {
+	switch sortBy {
 	case "cpu.request":
 		sort.Slice(nodeInfoList, func(i, j int) bool {
 			return nodeInfoList[i].CPUPercentage < nodeInfoList[j].CPUPercentage
 		})
 	case "cpu.util":
 		sort.Slice(nodeInfoList, func(i, j int) bool {
-			cpuUtilI, err := strconv.ParseFloat(strings.TrimSuffix(nodeInfoList[i].NodeCPUUtilization, "%"), 64)
-			kube.Error(err, "cpu使用率转换失败")
-
-			cpuUtilJ, err := strconv.ParseFloat(strings.TrimSuffix(nodeInfoList[j].NodeCPUUtilization, "%"), 64)
-			kube.Error(err, "cpu使用率转换失败")
-
-			return cpuUtilI > cpuUtilJ
+			return nodeInfoList[i].NodeCPUUtilization > nodeInfoList[j].NodeCPUUtilization
 		})
 	case "mem.request":
 		sort.Slice(nodeInfoList, func(i, j int) bool {
@@ -164,62 +330,222 @@ func GetNodeResource(ctx context.Context) {
 		})
 	case "mem.util":
 		sort.Slice(nodeInfoList, func(i, j int) bool {
-			memUtilI, err := strconv.ParseFloat(strings.TrimSuffix(nodeInfoList[i].NodeMemUtilization, "%"), 64)
-			kube.Error(err, "内存使用率转换失败")
-
-			memUtilJ, err := strconv.ParseFloat(strings.TrimSuffix(nodeInfoList[j].NodeMemUtilization, "%"), 64)
-			kube.Error(err, "内存使用率转换失败")
-			return memUtilI > memUtilJ
+			return nodeInfoList[i].NodeMemUtilization > nodeInfoList[j].NodeMemUtilization
+		})
+	case "cpu.overcommit":
+		sort.Slice(nodeInfoList, func(i, j int) bool {
+			return nodeInfoList[i].CPUOvercommit > nodeInfoList[j].CPUOvercommit
+		})
+	case "mem.overcommit":
+		sort.Slice(nodeInfoList, func(i, j int) bool {
+			return nodeInfoList[i].MemoryOvercommit > nodeInfoList[j].MemoryOvercommit
 		})
 	default:
-		fmt.Println("未知的排序选项")
+		return false
+	}
+	return true
+}
+
+// 去掉不可调度的节点，返回保留的节点及被去掉的节点名称
+func filterSchedulable(nodeInfoList []nodeInfo) ([]nodeInfo, []string) {
+	kept := make([]nodeInfo, 0, len(nodeInfoList))
+	var excluded []string
+	for _, info := range nodeInfoList {
+		if info.Schedulable {
+			kept = append(kept, info)
+		} else {
+			excluded = append(excluded, info.NodeName)
+		}
+	}
+	return kept, excluded
+}
+
+func printExcludedNodes(w io.Writer, excluded []string) {
+	if len(excluded) == 0 {
 		return
 	}
+	fmt.Fprintf(w, "\n已排除不可调度节点: %d个 (%s)\n", len(excluded), strings.Join(excluded, ", "))
+}
 
-	nodeResults := make([][]string, 0)
+func PrintNodeInfo(nodeInfoList []nodeInfo) {
+	header, rows := nodeTableRows(nodeInfoList)
+	renderRows(os.Stdout, header, rows)
+}
+
+// 输出待调度pod的数量、request合计及明细，没有待调度pod时不输出
+func PrintPendingDemand(w io.Writer, pending []pendingPod) {
+	if len(pending) == 0 {
+		return
+	}
+	var cpu, memory int64
+	rows := make([]tableRow, 0, len(pending))
+	for _, pod := range pending {
+		cpu += pod.CPURequests
+		memory += pod.MemRequests
+		rows = append(rows, tableRow{Cells: []string{pod.Namespace, pod.PodName, convertToUnits(pod.CPURequests, "CPU"), convertToUnits(pod.MemRequests, "Memory")}})
+	}
+	fmt.Fprintf(w, "\n待调度pod: %d个，request合计 cpu %s，内存 %s\n", len(pending), convertToUnits(cpu, "CPU"), convertToUnits(memory, "Memory"))
+	renderRows(w, pendingHeader, rows)
+}
+
+func nodeTableRows(nodeInfoList []nodeInfo) ([]string, []tableRow) {
+	nodeResults := make([]tableRow, 0)
 	// 输出结果
 	for _, nodeInfo := range nodeInfoList {
+		if outputFormat == outputWide {
+			nodeResults = append(nodeResults, nodeWideRow(nodeInfo))
+			continue
+		}
 		result := []string{
 			nodeInfo.NodeName,
+			formatNodeStatus(nodeInfo.Status),
+			formatTaints(nodeInfo.Taints),
 			// strconv.FormatInt(nodeInfo.CPUTotal, 10),
 			// strconv.FormatInt(nodeInfo.CPUAllocated, 10),
 			// strconv.FormatInt(nodeInfo.CPURemaining, 10),
-			colorize(nodeInfo.CPUPercentage),
-			nodeInfo.NodeCPUUtilization,
+			colorize("node.cpu.request-remaining", nodeInfo.CPUPercentage, fmt.Sprintf("%.2f%%", nodeInfo.CPUPercentage)),
+			nodeInfo.utilizationCell("node.cpu.util", nodeInfo.NodeCPUUtilization),
+			ratioCell("node.cpu.overcommit", nodeInfo.CPUOvercommit),
 			// strconv.FormatInt(nodeInfo.MemoryTotal, 10),
 			// strconv.FormatInt(nodeInfo.MemoryAllocated, 10),
 			// strconv.FormatInt(nodeInfo.MemoryRemaining, 10),
-			colorize(nodeInfo.MemoryPercentage),
-			nodeInfo.NodeMemUtilization,
+			colorize("node.mem.request-remaining", nodeInfo.MemoryPercentage, fmt.Sprintf("%.2f%%", nodeInfo.MemoryPercentage)),
+			nodeInfo.utilizationCell("node.mem.util", nodeInfo.NodeMemUtilization),
+			ratioCell("node.mem.overcommit", nodeInfo.MemoryOvercommit),
 		}
-		nodeResults = append(nodeResults, result)
+		nodeResults = append(nodeResults, tableRow{
+			Key:    nodeInfo.NodeName,
+			Ratios: []float64{nodeInfo.CPUPercentage, nodeInfo.NodeCPUUtilization, nodeInfo.MemoryPercentage, nodeInfo.NodeMemUtilization, nodeInfo.CPUOvercommit, nodeInfo.MemoryOvercommit},
+			Cells:  result,
+		})
+	}
+
+	if outputFormat == outputWide {
+		return nodeWideHeader, nodeResults
+	}
+	return nodeHeader, nodeResults
+}
+
+// -o wide 的表格行，在比例之外输出CPU(核)及内存(GiB)的绝对值和pod数
+func nodeWideRow(nodeInfo nodeInfo) tableRow {
+	return tableRow{
+		Key:    nodeInfo.NodeName,
+		Ratios: []float64{nodeInfo.CPUPercentage, nodeInfo.NodeCPUUtilization, nodeInfo.MemoryPercentage, nodeInfo.NodeMemUtilization, nodeInfo.CPUOvercommit, nodeInfo.MemoryOvercommit},
+		Cells: []string{
+			nodeInfo.NodeName,
+			formatNodeStatus(nodeInfo.Status),
+			formatTaints(nodeInfo.Taints),
+			joinValues(formatCores, nodeInfo.CPUTotal, nodeInfo.CPUCapacity),
+			strings.Join([]string{formatCores(nodeInfo.CPUAllocated), formatCores(nodeInfo.CPULimits), nodeInfo.usageCell(formatCores, nodeInfo.CPUUsage), formatCores(nodeInfo.CPURemaining)}, "|"),
+			colorize("node.cpu.request-remaining", nodeInfo.CPUPercentage, fmt.Sprintf("%.2f%%", nodeInfo.CPUPercentage)),
+			nodeInfo.utilizationCell("node.cpu.util", nodeInfo.NodeCPUUtilization),
+			ratioCell("node.cpu.overcommit", nodeInfo.CPUOvercommit),
+			joinValues(formatGiB, nodeInfo.MemoryTotal, nodeInfo.MemoryCapacity),
+			strings.Join([]string{formatGiB(nodeInfo.MemoryAllocated), formatGiB(nodeInfo.MemoryLimits), nodeInfo.usageCell(formatGiB, nodeInfo.MemoryUsage), formatGiB(nodeInfo.MemoryRemaining)}, "|"),
+			colorize("node.mem.request-remaining", nodeInfo.MemoryPercentage, fmt.Sprintf("%.2f%%", nodeInfo.MemoryPercentage)),
+			nodeInfo.utilizationCell("node.mem.util", nodeInfo.NodeMemUtilization),
+			ratioCell("node.mem.overcommit", nodeInfo.MemoryOvercommit),
+			fmt.Sprintf("%d|%d", nodeInfo.Pods, nodeInfo.PodCapacity),
+		},
 	}
+}
 
-	table := kube.NewTable()
-	// table.SetHeader([]string{"节点名称", "cpu总量(m)", "cpu已分配(m)", "cpu剩余值(m)", "cpu剩余百分比", "cpu实际使用", "内存总量(MiB)", "内存已分配(MiB)", "内存剩余值(MiB)", "内存剩余百分比", "内存实际使用"})
-	table.SetHeader(nodeHeader)
-	table.AppendBulk(nodeResults)
-	table.Render()
+// 没有metrics的节点用量未知，输出-
+func (n nodeInfo) utilizationCell(column string, utilization float64) string {
+	if !n.MetricsAvailable {
+		return "-"
+	}
+	return colorize(column, utilization, fmt.Sprintf("%.2f%%", utilization))
 }
 
-// 获取节点已经分配的资源
+func (n nodeInfo) usageCell(format func(int64) string, usage int64) string {
+	if !n.MetricsAvailable {
+		return "-"
+	}
+	return format(usage)
+}
+
+func joinValues(format func(int64) string, values ...int64) string {
+	formatted := make([]string, 0, len(values))
+	for _, v := range values {
+		formatted = append(formatted, format(v))
+	}
+	return strings.Join(formatted, "|")
+}
+
+// 毫核 -> 核
+func formatCores(milli int64) string {
+	return strconv.FormatFloat(float64(milli)/1000, 'f', 2, 64)
+}
+
+// 字节 -> GiB
+func formatGiB(bytes int64) string {
+	return strconv.FormatFloat(float64(bytes)/(1<<30), 'f', 2, 64)
+}
+
+// 获取pod在节点上占用的request，与调度器及pod命令的计算方式一致
 func calculatePodRequests(pod v1.Pod) (cpu, memory int64) {
-	for _, container := range pod.Spec.Containers {
-		cpuRequest := container.Resources.Requests[v1.ResourceCPU]
-		memoryRequest := container.Resources.Requests[v1.ResourceMemory]
-		cpu += cpuRequest.MilliValue()
-		memory += memoryRequest.Value() / Mebibyte // 将字节转换为 MB
+	requests := newPodResource(pod).effectiveResources()
+	return requests.CPURequests, requests.MemRequest
+}
+
+// 按kubectl get node的方式汇总Ready状态、cordon及压力状况，并判断节点是否可调度
+func getNodeStatus(node v1.Node) (string, bool) {
+	ready := "Unknown"
+	var pressures []string
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			switch condition.Status {
+			case v1.ConditionTrue:
+				ready = "Ready"
+			case v1.ConditionFalse:
+				ready = "NotReady"
+			}
+			continue
+		}
+		for _, pressure := range nodePressureConditions {
+			if condition.Type == pressure && condition.Status == v1.ConditionTrue {
+				pressures = append(pressures, string(condition.Type))
+			}
+		}
+	}
+
+	statuses := []string{ready}
+	if node.Spec.Unschedulable {
+		statuses = append(statuses, "SchedulingDisabled")
+	}
+	statuses = append(statuses, pressures...)
+
+	schedulable := ready == "Ready" && !node.Spec.Unschedulable
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute {
+			schedulable = false
+		}
 	}
+	return strings.Join(statuses, ","), schedulable
+}
 
-	if len(pod.Spec.InitContainers) > 0 {
-		initContainer := pod.Spec.InitContainers[0]
-		cpuRequest := initContainer.Resources.Requests[v1.ResourceCPU]
-		memoryRequest := initContainer.Resources.Requests[v1.ResourceMemory]
-		cpu += cpuRequest.MilliValue()
-		memory += memoryRequest.Value() / Mebibyte // 将字节转换为 MB
+func nodeTaints(node v1.Node) []string {
+	var taints []string
+	for _, taint := range node.Spec.Taints {
+		taints = append(taints, taint.ToString())
 	}
+	return taints
+}
+
+// 分组小计等没有状态的行输出-
+func formatNodeStatus(status string) string {
+	if status == "" {
+		return "-"
+	}
+	return status
+}
 
-	return cpu, memory
+func formatTaints(taints []string) string {
+	if len(taints) == 0 {
+		return "-"
+	}
+	return strings.Join(taints, ",")
 }
 
 // 获取节点的可分配的CPU及内存
@@ -227,7 +553,7 @@ func getNodeAllocatable(node v1.Node) (cpu, memory int64) {
 	cpuRequest := node.Status.Allocatable[v1.ResourceCPU]       // cpu 可分配值
 	memoryRequest := node.Status.Allocatable[v1.ResourceMemory] // 内存 可分配值
 
-	return cpuRequest.MilliValue(), memoryRequest.Value() / Mebibyte // 将字节转换为 MB
+	return cpuRequest.MilliValue(), memoryRequest.Value()
 }
 
 // 获取节点的最大的CPU及内存
@@ -235,7 +561,7 @@ func getNodeCapacity(node v1.Node) (cpu, memory int64) {
 	cpuRequest := node.Status.Capacity[v1.ResourceCPU]       // cpu 可分配值
 	memoryRequest := node.Status.Capacity[v1.ResourceMemory] // 内存 可分配值
 
-	return cpuRequest.MilliValue(), memoryRequest.Value() / Mebibyte // 将字节转换为 MB
+	return cpuRequest.MilliValue(), memoryRequest.Value()
 }
 
 // 计算剩余资源值
@@ -248,17 +574,23 @@ func calculateRemaining(request, allocated int64) int64 {
 }
 
 // 返回节点实际资源使用率
-func getNodeUtilization(ctx context.Context, nodeMap map[string]v1.Node) map[string]nodeMetrics {
-	nodeMetricsList, err := kube.GetMetricsClient().MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
-	kube.Error(err, "列出所有节点metrics指标失败")
+func getNodeUtilization(ctx context.Context, nodeMap map[string]v1.Node, selector Selector) (map[string]nodeMetrics, error) {
+	client, err := kube.GetMetricsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeMetricsList, err := client.MetricsV1beta1().NodeMetricses().List(ctx, selector.MetricsListOptions())
+	if err != nil {
+		return nil, kube.MetricsError(err, "列出所有节点metrics指标失败")
+	}
 
 	// 定义映射来存储每个节点的资源使用百分比
 	nodeMetricsMap := make(map[string]nodeMetrics)
 
 	for _, nm := range nodeMetricsList.Items {
 		nodeName := nm.Name
-		cpuUsage := nm.Usage.Cpu().MilliValue()          // 将毫核转换为核
-		memUsage := nm.Usage.Memory().Value() / Mebibyte // 将字节转换为 MB
+		cpuUsage := nm.Usage.Cpu().MilliValue() // 毫核
+		memUsage := nm.Usage.Memory().Value()
 
 		// 从映射中获取节点信息
 		node := nodeMap[nodeName]
@@ -269,21 +601,19 @@ func getNodeUtilization(ctx context.Context, nodeMap map[string]v1.Node) map[str
 		memPercentage := calculateRemaingPercentage(memUsage, memTotal)
 
 		nodeMetricsMap[nodeName] = nodeMetrics{
-			cpuPercentage: fmt.Sprintf("%.2f%%", cpuPercentage),
-			memPercentage: fmt.Sprintf("%.2f%%", memPercentage),
+			cpuUsage:      cpuUsage,
+			memUsage:      memUsage,
+			cpuPercentage: cpuPercentage,
+			memPercentage: memPercentage,
 		}
 	}
 
-	return nodeMetricsMap
+	return nodeMetricsMap, nil
 }
 
 func calculateRemaingPercentage(remain, total int64) float64 {
-	return float64(remain) / float64(total) * 100
-}
-
-func colorize(percentage float64) string {
-	if percentage < watermark {
-		return fmt.Sprintf("\x1b[31m%.2f%%\x1b[0m", percentage)
+	if total == 0 {
+		return 0 // 与calculateRatio一致，避免NaN导致json序列化失败
 	}
-	return fmt.Sprintf("%.2f%%", percentage)
+	return float64(remain) / float64(total) * 100
 }
@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func newNode(name, cpu, mem string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: resourceList(cpu, mem),
+			Capacity:    resourceList(cpu, mem),
+		},
+	}
+}
+
+func newNodeMetrics(name, cpu, mem string) metricsv1beta1.NodeMetrics {
+	return metricsv1beta1.NodeMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Usage:      resourceList(cpu, mem),
+	}
+}
+
+func TestLoadNodeInfo(t *testing.T) {
+	// kubelet不可达的NotReady节点通常没有metrics
+	notReady := newNode("node-c", "2", "4G")
+	notReady.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}}
+	ctx := fakeContext(t,
+		[]runtime.Object{
+			newNode("node-a", "4", "8G"),
+			newNode("node-b", "2", "4G"),
+			notReady,
+			newNode("node-zero", "0", "0"), // 可分配资源为0
+			newPod("default", "web-app-1", "node-a", newContainer("app", resourceList("1", "2G"), nil)),
+			newPod("default", "web-app-2", "node-a", newContainer("app", resourceList("1", "2G"), nil)),
+			newPod("kube-system", "dns-1", "node-b", newContainer("dns", resourceList("500m", "1G"), nil)),
+		},
+		nil,
+		[]metricsv1beta1.NodeMetrics{
+			newNodeMetrics("node-a", "1", "2G"),
+			newNodeMetrics("node-b", "1", "3G"),
+			newNodeMetrics("node-zero", "100m", "100M"),
+		})
+
+	tests := []struct {
+		node      string
+		cpuRemain float64
+		memRemain float64
+		cpuUtil   float64
+		memUtil   float64
+		noMetrics bool
+	}{
+		{node: "node-a", cpuRemain: 50, memRemain: 50, cpuUtil: 25, memUtil: 25},
+		{node: "node-b", cpuRemain: 75, memRemain: 75, cpuUtil: 50, memUtil: 75},
+		{node: "node-c", cpuRemain: 100, memRemain: 100, noMetrics: true}, // 无metrics的节点保留，用量未知
+		{node: "node-zero"}, // 分母为0时比例为0而不是NaN
+	}
+
+	nodeInfoList, err := LoadNodeInfo(ctx, Selector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]nodeInfo)
+	for _, info := range nodeInfoList {
+		byName[info.NodeName] = info
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.node, func(t *testing.T) {
+			info, ok := byName[tt.node]
+			if !ok {
+				t.Fatalf("node %s missing", tt.node)
+			}
+			if info.MetricsAvailable == tt.noMetrics {
+				t.Errorf("MetricsAvailable = %v, want %v", info.MetricsAvailable, !tt.noMetrics)
+			}
+			if !almostEqual(info.CPUPercentage, tt.cpuRemain) || !almostEqual(info.MemoryPercentage, tt.memRemain) {
+				t.Errorf("remaining = %.2f/%.2f, want %.2f/%.2f", info.CPUPercentage, info.MemoryPercentage, tt.cpuRemain, tt.memRemain)
+			}
+			if !almostEqual(info.NodeCPUUtilization, tt.cpuUtil) || !almostEqual(info.NodeMemUtilization, tt.memUtil) {
+				t.Errorf("utilization = %.2f/%.2f, want %.2f/%.2f", info.NodeCPUUtilization, info.NodeMemUtilization, tt.cpuUtil, tt.memUtil)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := writeNodeRecords(&buf, outputJSON, nodeInfoList); err != nil {
+		t.Errorf("node -o json: %v", err)
+	}
+}
+
+func TestNodeWithoutMetrics(t *testing.T) {
+	info := nodeInfo{NodeName: "node-c", Status: "NotReady", CPUTotal: 2000, CPUCapacity: 2000, CPURemaining: 2000, CPUPercentage: 100,
+		MemoryTotal: 4e9, MemoryCapacity: 4e9, MemoryRemaining: 4e9, MemoryPercentage: 100}
+
+	_, rows := nodeTableRows([]nodeInfo{info})
+	if cells := rows[0].Cells; cells[1] != "NotReady" || cells[4] != "-" || cells[7] != "-" {
+		t.Errorf("table row = %q, want NotReady with unknown utilization", cells)
+	}
+	if cell := nodeWideRow(info).Cells[4]; cell != "0.00|0.00|-|2.00" {
+		t.Errorf("wide cpu cell = %q", cell)
+	}
+
+	var buf bytes.Buffer
+	if err := writeNodeRecords(&buf, outputJSON, []nodeInfo{info}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"cpuUsageMilli": null`, `"memUtilizationPercent": null`, `"status": "NotReady"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("json missing %s:\n%s", want, buf.String())
+		}
+	}
+
+	thresholds, err := parseThresholds([]string{"node.cpu.util<50"}, "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if violations := nodeViolations(thresholds, []nodeInfo{info}); len(violations) != 0 {
+		t.Errorf("unknown utilization should not be checked: %+v", violations)
+	}
+	if kept, _ := filterSchedulable([]nodeInfo{info}); len(kept) != 0 {
+		t.Errorf("NotReady node should be excluded by --exclude-unschedulable")
+	}
+}
+
+func TestSortNodeInfo(t *testing.T) {
+	tests := []struct {
+		sortBy string
+		want   []string
+		ok     bool
+	}{
+		{sortBy: "cpu.request", want: []string{"node-b", "node-c", "node-a"}, ok: true},
+		{sortBy: "mem.request", want: []string{"node-a", "node-c", "node-b"}, ok: true},
+		{sortBy: "cpu.util", want: []string{"node-a", "node-b", "node-c"}, ok: true},
+		{sortBy: "mem.util", want: []string{"node-c", "node-b", "node-a"}, ok: true},
+		{sortBy: "cpu.overcommit", want: []string{"node-b", "node-a", "node-c"}, ok: true},
+		{sortBy: "mem.overcommit", want: []string{"node-c", "node-a", "node-b"}, ok: true},
+		{sortBy: "unknown", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			list := []nodeInfo{
+				{NodeName: "node-a", CPUPercentage: 80, MemoryPercentage: 10, NodeCPUUtilization: 90, NodeMemUtilization: 10, CPUOvercommit: 150, MemoryOvercommit: 90},
+				{NodeName: "node-b", CPUPercentage: 20, MemoryPercentage: 60, NodeCPUUtilization: 50, NodeMemUtilization: 40, CPUOvercommit: 300},
+				{NodeName: "node-c", CPUPercentage: 40, MemoryPercentage: 30, NodeCPUUtilization: 5, NodeMemUtilization: 70, CPUOvercommit: 80, MemoryOvercommit: 120},
+			}
+
+			if ok := SortNodeInfo(list, tt.sortBy); ok != tt.ok {
+				t.Fatalf("SortNodeInfo ok = %v, want %v", ok, tt.ok)
+			}
+			for i, name := range tt.want {
+				if list[i].NodeName != name {
+					t.Fatalf("position %d = %s, want %s", i, list[i].NodeName, name)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculatePodRequests(t *testing.T) {
+	sidecar := newContainer("proxy", resourceList("50m", "100M"), nil)
+	always := corev1.ContainerRestartPolicyAlways
+	sidecar.RestartPolicy = &always
+
+	pod := newPod("default", "web", "node-a",
+		newContainer("app", resourceList("100m", "100M"), nil),
+		newContainer("worker", resourceList("200m", "100M"), nil))
+	pod.Spec.InitContainers = []corev1.Container{
+		newContainer("migrate", resourceList("500m", "50M"), nil),
+		sidecar,
+		newContainer("warmup", resourceList("400m", "150M"), nil),
+	}
+	pod.Spec.Overhead = resourceList("10m", "0")
+
+	// cpu: init峰值500m大于业务容器与sidecar之和350m，再加overhead
+	// 内存: 业务容器与sidecar之和300M大于init峰值(warmup运行时加上sidecar为250M)
+	cpu, mem := calculatePodRequests(*pod)
+	if cpu != 510 || mem != 300e6 {
+		t.Errorf("calculatePodRequests = %dm/%d, want 510m/300000000", cpu, mem)
+	}
+
+	podInfo := &PodInfo{PodResource: newPodResource(*pod)}
+	if got := podInfo.totals(); got.CPURequests != cpu || got.MemRequests != mem {
+		t.Errorf("pod totals %+v differ from node accounting %dm/%d", got, cpu, mem)
+	}
+	peak, sidecars := podInfo.PodResource.initPeak()
+	if peak.CPURequests != 500 || peak.MemRequest != 250e6 || sidecars.CPURequests != 50 {
+		t.Errorf("initPeak = %+v, sidecars = %+v", peak, sidecars)
+	}
+}
+
+func TestLoadNodeStateExcludesTerminatedPods(t *testing.T) {
+	withPhase := func(pod *corev1.Pod, phase corev1.PodPhase) *corev1.Pod {
+		pod.Status.Phase = phase
+		return pod
+	}
+	ctx := fakeContext(t,
+		[]runtime.Object{
+			newNode("node-a", "4", "8G"),
+			withPhase(newPod("default", "web-1", "node-a", newContainer("app", resourceList("1", "2G"), resourceList("6", "4G"))), corev1.PodRunning),
+			withPhase(newPod("default", "starting-1", "node-a", newContainer("app", resourceList("1", "2G"), nil)), corev1.PodPending),
+			withPhase(newPod("default", "job-done", "node-a", newContainer("job", resourceList("2", "4G"), nil)), corev1.PodSucceeded),
+			withPhase(newPod("default", "job-failed", "node-a", newContainer("job", resourceList("2", "4G"), nil)), corev1.PodFailed),
+			withPhase(newPod("default", "web-3", "", newContainer("app", resourceList("500m", "1G"), nil)), corev1.PodPending),
+			withPhase(newPod("batch", "etl-1", "", newContainer("etl", resourceList("2", "3G"), nil)), corev1.PodPending),
+		},
+		nil,
+		[]metricsv1beta1.NodeMetrics{newNodeMetrics("node-a", "1", "2G")})
+
+	nodeInfoList, pending, err := loadNodeState(ctx, Selector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeInfoList) != 1 || nodeInfoList[0].CPUAllocated != 2000 || nodeInfoList[0].MemoryAllocated != 4e9 {
+		t.Fatalf("node totals = %+v, want 2 cpu/4G from running and bound pending pods", nodeInfoList)
+	}
+	if nodeInfoList[0].Pods != 2 {
+		t.Errorf("pods = %d, want 2", nodeInfoList[0].Pods)
+	}
+	// 只有web-1设置了limit，未设置limit的容器不计入超售率
+	if info := nodeInfoList[0]; info.CPUOvercommit != 150 || info.MemoryOvercommit != 50 {
+		t.Errorf("overcommit = %.2f/%.2f, want 150/50", info.CPUOvercommit, info.MemoryOvercommit)
+	}
+	if len(pending) != 2 || pending[0].PodName != "etl-1" || pending[1].PodName != "web-3" || pending[1].CPURequests != 500 {
+		t.Fatalf("pending = %+v, want etl-1 and web-3", pending)
+	}
+
+	var buf bytes.Buffer
+	PrintPendingDemand(&buf, pending)
+	if !strings.Contains(buf.String(), "待调度pod: 2个") {
+		t.Errorf("pending summary missing count:\n%s", buf.String())
+	}
+}
+
+func TestNodeWideRow(t *testing.T) {
+	info := nodeInfo{
+		NodeName: "node-a", CPUTotal: 3920, CPUCapacity: 4000, CPUAllocated: 2000, CPULimits: 6000, CPUUsage: 1500, CPURemaining: 1920,
+		MemoryTotal: 7 << 30, MemoryCapacity: 8 << 30, MemoryAllocated: 3 << 29, MemoryLimits: 6 << 30, MemoryUsage: 2 << 30, MemoryRemaining: 11 << 29,
+		Pods: 12, PodCapacity: 110, CPUOvercommit: 153.06, MemoryOvercommit: 85.71, Status: "Ready", MetricsAvailable: true,
+	}
+	row := nodeWideRow(info)
+	want := map[int]string{1: "Ready", 2: "-", 3: "3.92|4.00", 4: "2.00|6.00|1.50|1.92", 7: "153.06%", 8: "7.00|8.00", 9: "1.50|6.00|2.00|5.50", 12: "85.71%", 13: "12|110"}
+	for i, cell := range want {
+		if row.Cells[i] != cell {
+			t.Errorf("cell %d (%s) = %q, want %q", i, nodeWideHeader[i], row.Cells[i], cell)
+		}
+	}
+	if len(row.Cells) != len(nodeWideHeader) {
+		t.Errorf("got %d cells for %d columns", len(row.Cells), len(nodeWideHeader))
+	}
+}
+
+func TestGroupNodes(t *testing.T) {
+	if _, err := parseNodeGroupBy("topology.kubernetes.io/zone"); err == nil {
+		t.Error("parseNodeGroupBy without label: succeeded, want error")
+	}
+	key, err := parseNodeGroupBy("label:topology.kubernetes.io/zone")
+	if err != nil || key != "topology.kubernetes.io/zone" {
+		t.Fatalf("parseNodeGroupBy = %q, %v", key, err)
+	}
+
+	zone := func(info nodeInfo, value string) nodeInfo {
+		info.Labels = map[string]string{key: value}
+		info.MetricsAvailable = true
+		return info
+	}
+	nodes := []nodeInfo{
+		zone(nodeInfo{NodeName: "a-1", CPUTotal: 4000, CPURemaining: 1000, CPUCapacity: 4000, CPUUsage: 2000, MemoryTotal: 8e9, MemoryRemaining: 2e9, MemoryCapacity: 8e9, Pods: 3, PodCapacity: 110}, "zone-a"),
+		zone(nodeInfo{NodeName: "b-1", CPUTotal: 4000, CPURemaining: 3000, CPUCapacity: 4000, CPUUsage: 400, MemoryTotal: 8e9, MemoryRemaining: 6e9, MemoryCapacity: 8e9, Pods: 1, PodCapacity: 110}, "zone-b"),
+		zone(nodeInfo{NodeName: "a-2", CPUTotal: 4000, CPURemaining: 1000, CPUCapacity: 4000, CPUUsage: 2000, CPULimits: 8000, MemoryTotal: 8e9, MemoryRemaining: 2e9, MemoryCapacity: 8e9, Pods: 5, PodCapacity: 110}, "zone-a"),
+		{NodeName: "unlabeled", CPUTotal: 2000, CPURemaining: 1000, CPUCapacity: 2000, MemoryTotal: 4e9, MemoryRemaining: 1e9, MemoryCapacity: 4e9},
+	}
+
+	groups := groupNodes(nodes, key, "cpu.request")
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3", len(groups))
+	}
+	a := groups[0]
+	if a.Subtotal.NodeName != key+"=zone-a" || len(a.Nodes) != 2 || a.Nodes[0].NodeName != "a-1" {
+		t.Fatalf("first group = %+v, want zone-a with a-1, a-2", a)
+	}
+	if a.Subtotal.CPUPercentage != 25 || a.Subtotal.NodeCPUUtilization != 50 || a.Subtotal.CPUOvercommit != 100 || a.Subtotal.Pods != 8 || a.Subtotal.PodCapacity != 220 {
+		t.Errorf("zone-a subtotal = %+v", a.Subtotal)
+	}
+	if groups[1].Subtotal.NodeName != key+"="+missingLabelValue || groups[2].Subtotal.NodeName != key+"=zone-b" {
+		t.Errorf("group order = %s, %s, want unlabeled before zone-b", groups[1].Subtotal.NodeName, groups[2].Subtotal.NodeName)
+	}
+
+	header, rows := nodeGroupTableRows(groups)
+	if len(header) != len(nodeHeader) || len(rows) != 7 {
+		t.Fatalf("got %d rows, want 4 nodes and 3 subtotals", len(rows))
+	}
+	if got := rows[2].Cells[0]; got != "小计 "+key+"=zone-a (2)" {
+		t.Errorf("subtotal row = %q", got)
+	}
+}
+
+func TestNodeStatus(t *testing.T) {
+	withStatus := func(node *corev1.Node, ready corev1.ConditionStatus, unschedulable bool, taints ...corev1.Taint) *corev1.Node {
+		node.Status.Conditions = []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: ready},
+			{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+		}
+		node.Spec.Unschedulable = unschedulable
+		node.Spec.Taints = taints
+		return node
+	}
+	pressured := withStatus(newNode("pressured", "4", "8G"), corev1.ConditionTrue, false)
+	pressured.Status.Conditions = append(pressured.Status.Conditions, corev1.NodeCondition{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue})
+
+	tests := []struct {
+		node        *corev1.Node
+		status      string
+		schedulable bool
+	}{
+		{node: withStatus(newNode("ready", "4", "8G"), corev1.ConditionTrue, false), status: "Ready", schedulable: true},
+		{node: withStatus(newNode("cordoned", "4", "8G"), corev1.ConditionTrue, true), status: "Ready,SchedulingDisabled"},
+		{node: withStatus(newNode("down", "4", "8G"), corev1.ConditionFalse, false), status: "NotReady"},
+		{node: newNode("no-conditions", "4", "8G"), status: "Unknown"},
+		{node: withStatus(newNode("gpu", "4", "8G"), corev1.ConditionTrue, false, corev1.Taint{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}), status: "Ready"},
+		{node: withStatus(newNode("preferred", "4", "8G"), corev1.ConditionTrue, false, corev1.Taint{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule}), status: "Ready", schedulable: true},
+		{node: pressured, status: "Ready,DiskPressure", schedulable: true},
+	}
+	for _, tt := range tests {
+		status, schedulable := getNodeStatus(*tt.node)
+		if status != tt.status || schedulable != tt.schedulable {
+			t.Errorf("%s: got %q/%v, want %q/%v", tt.node.Name, status, schedulable, tt.status, tt.schedulable)
+		}
+	}
+	if got := formatTaints(nodeTaints(*tests[4].node)); got != "gpu=true:NoSchedule" {
+		t.Errorf("taints = %q", got)
+	}
+
+	nodes := []nodeInfo{{NodeName: "a", Schedulable: true}, {NodeName: "b"}, {NodeName: "c", Schedulable: true}}
+	kept, excluded := filterSchedulable(nodes)
+	if len(kept) != 2 || kept[1].NodeName != "c" || len(excluded) != 1 || excluded[0] != "b" {
+		t.Errorf("filterSchedulable = %+v, %v", kept, excluded)
+	}
+}
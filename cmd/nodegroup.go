@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	groupByLabelPrefix = "label:"
+	missingLabelValue  = "<未设置>"
+)
+
+var nodeGroupBy string
+
+// 同一标签值下的节点及其汇总，Subtotal的名称为 <key>=<value>
+type nodeGroup struct {
+	Nodes    []nodeInfo
+	Subtotal nodeInfo
+}
+
+// 解析 label:<key>，未指定时返回空字符串
+func parseNodeGroupBy(groupBy string) (string, error) {
+	if groupBy == "" {
+		return "", nil
+	}
+	key := strings.TrimPrefix(groupBy, groupByLabelPrefix)
+	if key == groupBy || key == "" {
+		return "", fmt.Errorf("--group-by 格式应为 label:<key>，如 label:topology.kubernetes.io/zone: %s", groupBy)
+	}
+	return key, nil
+}
+
+// 按节点标签key的值分组，组内保持nodeInfoList的顺序，各组按sortBy对小计排序
+func groupNodes(nodeInfoList []nodeInfo, key, sortBy string) []nodeGroup {
+	var values []string
+	members := make(map[string][]nodeInfo)
+	for _, info := range nodeInfoList {
+		value, ok := info.Labels[key]
+		if !ok {
+			value = missingLabelValue
+		}
+		if _, ok := members[value]; !ok {
+			values = append(values, value)
+		}
+		members[value] = append(members[value], info)
+	}
+
+	subtotals := make([]nodeInfo, 0, len(values))
+	for _, value := range values {
+		subtotals = append(subtotals, sumNodeInfo(key+"="+value, members[value]))
+	}
+	SortNodeInfo(subtotals, sortBy)
+
+	groups := make([]nodeGroup, 0, len(subtotals))
+	for _, subtotal := range subtotals {
+		value := strings.TrimPrefix(subtotal.NodeName, key+"=")
+		groups = append(groups, nodeGroup{Nodes: members[value], Subtotal: subtotal})
+	}
+	return groups
+}
+
+// 累加各节点的资源后重新计算比例，实际使用率与单个节点一样以容量为分母
+// 没有metrics的节点不计入实际使用率，组内都没有metrics时用量未知
+func sumNodeInfo(name string, nodes []nodeInfo) nodeInfo {
+	total := nodeInfo{NodeName: name}
+	var cpuMeasured, memoryMeasured int64 // 有metrics的节点的容量
+	for _, n := range nodes {
+		if n.MetricsAvailable {
+			total.MetricsAvailable = true
+			cpuMeasured += n.CPUCapacity
+			memoryMeasured += n.MemoryCapacity
+		}
+		total.CPUTotal += n.CPUTotal
+		total.CPUAllocated += n.CPUAllocated
+		total.CPURemaining += n.CPURemaining
+		total.CPUUsage += n.CPUUsage
+		total.CPUCapacity += n.CPUCapacity
+		total.CPULimits += n.CPULimits
+		total.MemoryTotal += n.MemoryTotal
+		total.MemoryAllocated += n.MemoryAllocated
+		total.MemoryRemaining += n.MemoryRemaining
+		total.MemoryUsage += n.MemoryUsage
+		total.MemoryCapacity += n.MemoryCapacity
+		total.MemoryLimits += n.MemoryLimits
+		total.Pods += n.Pods
+		total.PodCapacity += n.PodCapacity
+	}
+	total.CPUPercentage = calculateRatio(total.CPURemaining, total.CPUTotal)
+	total.NodeCPUUtilization = calculateRatio(total.CPUUsage, cpuMeasured)
+	total.CPUOvercommit = calculateRatio(total.CPULimits, total.CPUTotal)
+	total.MemoryPercentage = calculateRatio(total.MemoryRemaining, total.MemoryTotal)
+	total.NodeMemUtilization = calculateRatio(total.MemoryUsage, memoryMeasured)
+	total.MemoryOvercommit = calculateRatio(total.MemoryLimits, total.MemoryTotal)
+	return total
+}
+
+// 每组的节点行之后输出一行小计，小计行的名称为 小计 <key>=<value> (节点数)
+func nodeGroupTableRows(groups []nodeGroup) ([]string, []tableRow) {
+	var header []string
+	var rows []tableRow
+	for _, group := range groups {
+		var groupRows []tableRow
+		header, groupRows = nodeTableRows(group.Nodes)
+		rows = append(rows, groupRows...)
+
+		subtotal := group.Subtotal
+		subtotal.NodeName = fmt.Sprintf("小计 %s (%d)", subtotal.NodeName, len(group.Nodes))
+		_, subtotalRows := nodeTableRows([]nodeInfo{subtotal})
+		rows = append(rows, subtotalRows...)
+	}
+	if header == nil {
+		header, _ = nodeTableRows(nil)
+	}
+	return header, rows
+}
+
+func groupSubtotals(groups []nodeGroup) []nodeInfo {
+	subtotals := make([]nodeInfo, 0, len(groups))
+	for _, group := range groups {
+		subtotals = append(subtotals, group.Subtotal)
+	}
+	return subtotals
+}
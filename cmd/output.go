@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// -o/--output 支持的格式，为空时输出表格
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+	outputCSV  = "csv"
+	outputTSV  = "tsv"
+	outputWide = "wide" // 表格，仅node命令支持
+
+	outputAPIVersion = "kubetop/v1"
+)
+
+var outputFormat string
+
+/*
+结构化输出的schema，字段只增不改:
+  - CPU单位为毫核(*Milli)，内存单位为字节(*Bytes)
+  - *Ratio/*Percent 为百分比数值，取值0-100(用量可超过100)，分母为0时为0
+  - json/yaml 输出 {apiVersion, kind, items}，pod的容器明细位于 containers
+  - csv/tsv 首行为列名，与json字段名一致；pod命令使用 -c 时每行为一个容器
+*/
+
+type containerRecord struct {
+	Name                   string  `json:"name,omitempty"`
+	CPURequestMilli        int64   `json:"cpuRequestMilli"`
+	CPULimitMilli          int64   `json:"cpuLimitMilli"`
+	CPUUsageMilli          int64   `json:"cpuUsageMilli"`
+	CPUUsageToRequestRatio float64 `json:"cpuUsageToRequestRatio"`
+	CPUUsageToLimitRatio   float64 `json:"cpuUsageToLimitRatio"`
+	MemRequestBytes        int64   `json:"memRequestBytes"`
+	MemLimitBytes          int64   `json:"memLimitBytes"`
+	MemUsageBytes          int64   `json:"memUsageBytes"`
+	MemUsageToRequestRatio float64 `json:"memUsageToRequestRatio"`
+	MemUsageToLimitRatio   float64 `json:"memUsageToLimitRatio"`
+}
+
+type podRecord struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Node      string `json:"node"`
+	containerRecord
+	Containers []containerRecord `json:"containers"`
+}
+
+type nodeRecord struct {
+	Node                       string   `json:"node"`
+	CPUAllocatableMilli        int64    `json:"cpuAllocatableMilli"`
+	CPURequestedMilli          int64    `json:"cpuRequestedMilli"`
+	CPURemainingMilli          int64    `json:"cpuRemainingMilli"`
+	CPURequestRemainingPercent float64  `json:"cpuRequestRemainingPercent"`
+	CPUUsageMilli              *int64   `json:"cpuUsageMilli"` // 节点没有metrics时为null
+	CPUUtilizationPercent      *float64 `json:"cpuUtilizationPercent"`
+	MemAllocatableBytes        int64    `json:"memAllocatableBytes"`
+	MemRequestedBytes          int64    `json:"memRequestedBytes"`
+	MemRemainingBytes          int64    `json:"memRemainingBytes"`
+	MemRequestRemainingPercent float64  `json:"memRequestRemainingPercent"`
+	MemUsageBytes              *int64   `json:"memUsageBytes"`
+	MemUtilizationPercent      *float64 `json:"memUtilizationPercent"`
+	CPUCapacityMilli           int64    `json:"cpuCapacityMilli"`
+	CPULimitedMilli            int64    `json:"cpuLimitedMilli"`
+	MemCapacityBytes           int64    `json:"memCapacityBytes"`
+	MemLimitedBytes            int64    `json:"memLimitedBytes"`
+	Pods                       int64    `json:"pods"`
+	PodCapacity                int64    `json:"podCapacity"`
+	CPULimitOvercommitPercent  float64  `json:"cpuLimitOvercommitPercent"`
+	MemLimitOvercommitPercent  float64  `json:"memLimitOvercommitPercent"`
+	Status                     string   `json:"status"`
+	Taints                     []string `json:"taints"`
+	Schedulable                bool     `json:"schedulable"`
+}
+
+type workloadRecord struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Replicas  int    `json:"replicas"`
+	containerRecord
+}
+
+type recommendationRecord struct {
+	Namespace                  string          `json:"namespace"`
+	Kind                       string          `json:"kind"`
+	Name                       string          `json:"name"`
+	Container                  string          `json:"container"`
+	Replicas                   int             `json:"replicas"`
+	CPURequestMilli            int64           `json:"cpuRequestMilli"`
+	CPULimitMilli              int64           `json:"cpuLimitMilli"`
+	CPUUsageMilli              int64           `json:"cpuUsageMilli"`
+	RecommendedCPURequestMilli int64           `json:"recommendedCpuRequestMilli"`
+	RecommendedCPULimitMilli   int64           `json:"recommendedCpuLimitMilli"`
+	CPUStatus                  provisionStatus `json:"cpuStatus"`
+	MemRequestBytes            int64           `json:"memRequestBytes"`
+	MemLimitBytes              int64           `json:"memLimitBytes"`
+	MemUsageBytes              int64           `json:"memUsageBytes"`
+	RecommendedMemRequestBytes int64           `json:"recommendedMemRequestBytes"`
+	RecommendedMemLimitBytes   int64           `json:"recommendedMemLimitBytes"`
+	MemStatus                  provisionStatus `json:"memStatus"`
+}
+
+type recordList struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Items      interface{} `json:"items"`
+}
+
+// 校验输出格式，extra为命令额外支持的格式
+func validateOutputFormat(format string, extra ...string) error {
+	switch format {
+	case "", outputJSON, outputYAML, outputCSV, outputTSV:
+		return nil
+	}
+	for _, f := range extra {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("不支持的输出格式: %s，可选 json|yaml|csv|tsv", format)
+}
+
+func isStructuredOutput(format string) bool {
+	switch format {
+	case outputJSON, outputYAML, outputCSV, outputTSV:
+		return true
+	}
+	return false
+}
+
+func newPodRecord(podInfo *PodInfo) podRecord {
+	t := podInfo.totals()
+	record := podRecord{
+		Namespace: podInfo.PodResource.Namespace,
+		Pod:       podInfo.PodResource.PodName,
+		Node:      podInfo.NodeName,
+		containerRecord: containerRecord{
+			CPURequestMilli:        t.CPURequests,
+			CPULimitMilli:          t.CPULimits,
+			CPUUsageMilli:          t.CPUUsage,
+			CPUUsageToRequestRatio: podInfo.CPUUsageToRequestRatio,
+			CPUUsageToLimitRatio:   podInfo.CPUUsageToLimitsRatio,
+			MemRequestBytes:        t.MemRequests,
+			MemLimitBytes:          t.MemLimits,
+			MemUsageBytes:          t.MemUsage,
+			MemUsageToRequestRatio: podInfo.MemUsageToRequestRatio,
+			MemUsageToLimitRatio:   podInfo.MemUsageToLimitsRatio,
+		},
+		Containers: make([]containerRecord, 0, len(podInfo.PodResource.Containers)),
+	}
+
+	names := make([]string, 0, len(podInfo.PodResource.Containers))
+	for name := range podInfo.PodResource.Containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		resource := podInfo.PodResource.Containers[name]
+		container := containerRecord{
+			Name:            name,
+			CPURequestMilli: resource.CPURequests,
+			CPULimitMilli:   resource.CPULimits,
+			MemRequestBytes: resource.MemRequest,
+			MemLimitBytes:   resource.MemLimits,
+		}
+		if metric, ok := podInfo.PodMetrics.Containers[name]; ok {
+			container.CPUUsageMilli = metric.CPUUsage
+			container.MemUsageBytes = metric.MemUsage
+		}
+		if ratio, ok := podInfo.ContainersRatio[name]; ok {
+			container.CPUUsageToRequestRatio = ratio.CPUUsageToRequestRatio
+			container.CPUUsageToLimitRatio = ratio.CPUUsageToLimitsRatio
+			container.MemUsageToRequestRatio = ratio.MemUsageToRequestRatio
+			container.MemUsageToLimitRatio = ratio.MemUsageToLimitsRatio
+		}
+		record.Containers = append(record.Containers, container)
+	}
+	return record
+}
+
+func newNodeRecord(info nodeInfo) nodeRecord {
+	record := nodeRecord{
+		Node:                       info.NodeName,
+		CPUAllocatableMilli:        info.CPUTotal,
+		CPURequestedMilli:          info.CPUAllocated,
+		CPURemainingMilli:          info.CPURemaining,
+		CPURequestRemainingPercent: info.CPUPercentage,
+		MemAllocatableBytes:        info.MemoryTotal,
+		MemRequestedBytes:          info.MemoryAllocated,
+		MemRemainingBytes:          info.MemoryRemaining,
+		MemRequestRemainingPercent: info.MemoryPercentage,
+		CPUCapacityMilli:           info.CPUCapacity,
+		CPULimitedMilli:            info.CPULimits,
+		MemCapacityBytes:           info.MemoryCapacity,
+		MemLimitedBytes:            info.MemoryLimits,
+		Pods:                       info.Pods,
+		PodCapacity:                info.PodCapacity,
+		CPULimitOvercommitPercent:  info.CPUOvercommit,
+		MemLimitOvercommitPercent:  info.MemoryOvercommit,
+		Status:                     info.Status,
+		Taints:                     info.Taints,
+		Schedulable:                info.Schedulable,
+	}
+	if info.MetricsAvailable {
+		record.CPUUsageMilli, record.CPUUtilizationPercent = &info.CPUUsage, &info.NodeCPUUtilization
+		record.MemUsageBytes, record.MemUtilizationPercent = &info.MemoryUsage, &info.NodeMemUtilization
+	}
+	return record
+}
+
+var (
+	containerColumns = []string{"name", "cpuRequestMilli", "cpuLimitMilli", "cpuUsageMilli", "cpuUsageToRequestRatio", "cpuUsageToLimitRatio",
+		"memRequestBytes", "memLimitBytes", "memUsageBytes", "memUsageToRequestRatio", "memUsageToLimitRatio"}
+	nodeColumns = []string{"node", "cpuAllocatableMilli", "cpuRequestedMilli", "cpuRemainingMilli", "cpuRequestRemainingPercent", "cpuUsageMilli", "cpuUtilizationPercent",
+		"memAllocatableBytes", "memRequestedBytes", "memRemainingBytes", "memRequestRemainingPercent", "memUsageBytes", "memUtilizationPercent",
+		"cpuCapacityMilli", "cpuLimitedMilli", "memCapacityBytes", "memLimitedBytes", "pods", "podCapacity",
+		"cpuLimitOvercommitPercent", "memLimitOvercommitPercent", "status", "taints", "schedulable"}
+)
+
+func (c containerRecord) values() []string {
+	return []string{c.Name, formatInt(c.CPURequestMilli), formatInt(c.CPULimitMilli), formatInt(c.CPUUsageMilli), formatFloat(c.CPUUsageToRequestRatio), formatFloat(c.CPUUsageToLimitRatio),
+		formatInt(c.MemRequestBytes), formatInt(c.MemLimitBytes), formatInt(c.MemUsageBytes), formatFloat(c.MemUsageToRequestRatio), formatFloat(c.MemUsageToLimitRatio)}
+}
+
+func (n nodeRecord) values() []string {
+	return []string{n.Node, formatInt(n.CPUAllocatableMilli), formatInt(n.CPURequestedMilli), formatInt(n.CPURemainingMilli), formatFloat(n.CPURequestRemainingPercent), formatOptionalInt(n.CPUUsageMilli), formatOptionalFloat(n.CPUUtilizationPercent),
+		formatInt(n.MemAllocatableBytes), formatInt(n.MemRequestedBytes), formatInt(n.MemRemainingBytes), formatFloat(n.MemRequestRemainingPercent), formatOptionalInt(n.MemUsageBytes), formatOptionalFloat(n.MemUtilizationPercent),
+		formatInt(n.CPUCapacityMilli), formatInt(n.CPULimitedMilli), formatInt(n.MemCapacityBytes), formatInt(n.MemLimitedBytes), formatInt(n.Pods), formatInt(n.PodCapacity),
+		formatFloat(n.CPULimitOvercommitPercent), formatFloat(n.MemLimitOvercommitPercent), n.Status, strings.Join(n.Taints, ","), strconv.FormatBool(n.Schedulable)}
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// 未知的值在csv/tsv中输出为空
+func formatOptionalInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return formatInt(*v)
+}
+
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatFloat(*v)
+}
+
+// 以结构化格式输出pod，byContainer为true时csv/tsv每行为一个容器
+func writePodRecords(w io.Writer, format string, podInfoList []*PodInfo, byContainer bool) error {
+	records := make([]podRecord, 0, len(podInfoList))
+	for _, podInfo := range podInfoList {
+		records = append(records, newPodRecord(podInfo))
+	}
+
+	if format == outputJSON || format == outputYAML {
+		return writeRecordList(w, format, "PodUsageList", records)
+	}
+
+	header := append([]string{"namespace", "pod", "node"}, containerColumns[1:]...)
+	if byContainer {
+		header = append([]string{"namespace", "pod", "node", "container"}, containerColumns[1:]...)
+	}
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		if byContainer {
+			for _, container := range record.Containers {
+				rows = append(rows, append([]string{record.Namespace, record.Pod, record.Node}, container.values()...))
+			}
+			continue
+		}
+		rows = append(rows, append([]string{record.Namespace, record.Pod, record.Node}, record.containerRecord.values()[1:]...))
+	}
+	return writeDelimited(w, format, header, rows)
+}
+
+func writeNodeRecords(w io.Writer, format string, nodeInfoList []nodeInfo) error {
+	records := make([]nodeRecord, 0, len(nodeInfoList))
+	for _, info := range nodeInfoList {
+		records = append(records, newNodeRecord(info))
+	}
+
+	if format == outputJSON || format == outputYAML {
+		return writeRecordList(w, format, "NodeUsageList", records)
+	}
+
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, record.values())
+	}
+	return writeDelimited(w, format, nodeColumns, rows)
+}
+
+func writeWorkloadRecords(w io.Writer, format string, workloadList []*Workload) error {
+	records := make([]workloadRecord, 0, len(workloadList))
+	for _, workload := range workloadList {
+		records = append(records, workloadRecord{
+			Namespace: workload.Namespace,
+			Kind:      workload.Kind,
+			Name:      workload.Name,
+			Replicas:  workload.Replicas,
+			containerRecord: containerRecord{
+				CPURequestMilli:        workload.CPURequests,
+				CPULimitMilli:          workload.CPULimits,
+				CPUUsageMilli:          workload.CPUUsage,
+				CPUUsageToRequestRatio: workload.CPUUsageToRequestRatio,
+				CPUUsageToLimitRatio:   workload.CPUUsageToLimitsRatio,
+				MemRequestBytes:        workload.MemRequests,
+				MemLimitBytes:          workload.MemLimits,
+				MemUsageBytes:          workload.MemUsage,
+				MemUsageToRequestRatio: workload.MemUsageToRequestRatio,
+				MemUsageToLimitRatio:   workload.MemUsageToLimitsRatio,
+			},
+		})
+	}
+
+	if format == outputJSON || format == outputYAML {
+		return writeRecordList(w, format, "WorkloadUsageList", records)
+	}
+
+	header := append([]string{"namespace", "kind", "name", "replicas"}, containerColumns[1:]...)
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, append([]string{record.Namespace, record.Kind, record.Name, strconv.Itoa(record.Replicas)}, record.containerRecord.values()[1:]...))
+	}
+	return writeDelimited(w, format, header, rows)
+}
+
+var recommendationColumns = []string{"namespace", "kind", "name", "container", "replicas",
+	"cpuRequestMilli", "cpuLimitMilli", "cpuUsageMilli", "recommendedCpuRequestMilli", "recommendedCpuLimitMilli", "cpuStatus",
+	"memRequestBytes", "memLimitBytes", "memUsageBytes", "recommendedMemRequestBytes", "recommendedMemLimitBytes", "memStatus"}
+
+// 推荐值中的用量为各副本的最大值，status取值 ok|over|under
+func writeRecommendationRecords(w io.Writer, format string, recommendations []*Recommendation) error {
+	records := make([]recommendationRecord, 0, len(recommendations))
+	for _, r := range recommendations {
+		records = append(records, recommendationRecord{
+			Namespace:                  r.Namespace,
+			Kind:                       r.Kind,
+			Name:                       r.Name,
+			Container:                  r.Container,
+			Replicas:                   r.Replicas,
+			CPURequestMilli:            r.Current.CPURequests,
+			CPULimitMilli:              r.Current.CPULimits,
+			CPUUsageMilli:              r.CPUUsage,
+			RecommendedCPURequestMilli: r.Recommended.CPURequests,
+			RecommendedCPULimitMilli:   r.Recommended.CPULimits,
+			CPUStatus:                  r.CPUStatus,
+			MemRequestBytes:            r.Current.MemRequest,
+			MemLimitBytes:              r.Current.MemLimits,
+			MemUsageBytes:              r.MemUsage,
+			RecommendedMemRequestBytes: r.Recommended.MemRequest,
+			RecommendedMemLimitBytes:   r.Recommended.MemLimits,
+			MemStatus:                  r.MemStatus,
+		})
+	}
+
+	if format == outputJSON || format == outputYAML {
+		return writeRecordList(w, format, "RecommendationList", records)
+	}
+
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		rows = append(rows, []string{r.Namespace, r.Kind, r.Name, r.Container, strconv.Itoa(r.Replicas),
+			formatInt(r.CPURequestMilli), formatInt(r.CPULimitMilli), formatInt(r.CPUUsageMilli), formatInt(r.RecommendedCPURequestMilli), formatInt(r.RecommendedCPULimitMilli), string(r.CPUStatus),
+			formatInt(r.MemRequestBytes), formatInt(r.MemLimitBytes), formatInt(r.MemUsageBytes), formatInt(r.RecommendedMemRequestBytes), formatInt(r.RecommendedMemLimitBytes), string(r.MemStatus)})
+	}
+	return writeDelimited(w, format, recommendationColumns, rows)
+}
+
+func writeRecordList(w io.Writer, format, kind string, items interface{}) error {
+	list := recordList{APIVersion: outputAPIVersion, Kind: kind, Items: items}
+
+	var (
+		data []byte
+		err  error
+	)
+	if format == outputYAML {
+		data, err = yaml.Marshal(list)
+	} else {
+		data, err = json.MarshalIndent(list, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func writeDelimited(w io.Writer, format string, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if format == outputTSV {
+		writer.Comma = '\t'
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWritePodRecords(t *testing.T) {
+	podInfo := &PodInfo{
+		PodResource: PodResource{
+			Namespace: "default",
+			PodName:   "web-app-1",
+			NodeName:  "node-a",
+			Containers: map[string]*ContainerResource{
+				"app":     {Name: "app", CPURequests: 100, CPULimits: 200, MemRequest: 100e6, MemLimits: 200e6},
+				"sidecar": {Name: "sidecar", CPURequests: 100, MemRequest: 50e6},
+			},
+		},
+		PodMetrics: PodMetrics{
+			PodName: "web-app-1",
+			Containers: map[string]*ContainerMetrics{
+				"app":     {Name: "app", CPUUsage: 50, MemUsage: 50e6},
+				"sidecar": {Name: "sidecar", CPUUsage: 10, MemUsage: 10e6},
+			},
+		},
+	}
+	podInfo.calculateContainerMetrics()
+	podInfo.calculateTotalMetrics()
+
+	tests := []struct {
+		format      string
+		byContainer bool
+		want        string
+	}{
+		{
+			format: outputCSV,
+			want: "namespace,pod,node,cpuRequestMilli,cpuLimitMilli,cpuUsageMilli,cpuUsageToRequestRatio,cpuUsageToLimitRatio,memRequestBytes,memLimitBytes,memUsageBytes,memUsageToRequestRatio,memUsageToLimitRatio\n" +
+				"default,web-app-1,node-a,200,200,60,30.00,30.00,150000000,200000000,60000000,40.00,30.00\n",
+		},
+		{
+			format:      outputTSV,
+			byContainer: true,
+			want: "namespace\tpod\tnode\tcontainer\tcpuRequestMilli\tcpuLimitMilli\tcpuUsageMilli\tcpuUsageToRequestRatio\tcpuUsageToLimitRatio\tmemRequestBytes\tmemLimitBytes\tmemUsageBytes\tmemUsageToRequestRatio\tmemUsageToLimitRatio\n" +
+				"default\tweb-app-1\tnode-a\tapp\t100\t200\t50\t50.00\t25.00\t100000000\t200000000\t50000000\t50.00\t25.00\n" +
+				"default\tweb-app-1\tnode-a\tsidecar\t100\t0\t10\t10.00\t0.00\t50000000\t0\t10000000\t20.00\t0.00\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writePodRecords(&buf, tt.format, []*PodInfo{podInfo}, tt.byContainer); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run(outputJSON, func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writePodRecords(&buf, outputJSON, []*PodInfo{podInfo}, false); err != nil {
+			t.Fatal(err)
+		}
+		var list struct {
+			APIVersion string      `json:"apiVersion"`
+			Kind       string      `json:"kind"`
+			Items      []podRecord `json:"items"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		if list.APIVersion != outputAPIVersion || list.Kind != "PodUsageList" || len(list.Items) != 1 {
+			t.Fatalf("unexpected list header: %+v", list)
+		}
+		item := list.Items[0]
+		if item.CPUUsageMilli != 60 || item.MemRequestBytes != 150e6 || len(item.Containers) != 2 || item.Containers[0].Name != "app" {
+			t.Errorf("unexpected item: %+v", item)
+		}
+		if strings.Contains(buf.String(), "\x1b[") {
+			t.Error("structured output must not contain ANSI escapes")
+		}
+	})
+}
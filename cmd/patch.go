@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// --patch 支持的格式
+const (
+	patchStrategic = "strategic"
+	patchKustomize = "kustomize"
+)
+
+var (
+	patchFormat string
+	patchDir    string
+)
+
+// 可打补丁的工作负载类型，path为pod spec在对象中的位置
+// 裸pod及Job的pod模板不可修改，不生成补丁
+var patchTargets = map[string]struct {
+	apiVersion string
+	path       []string
+}{
+	"Deployment":  {"apps/v1", []string{"spec", "template", "spec"}},
+	"StatefulSet": {"apps/v1", []string{"spec", "template", "spec"}},
+	"DaemonSet":   {"apps/v1", []string{"spec", "template", "spec"}},
+	"ReplicaSet":  {"apps/v1", []string{"spec", "template", "spec"}},
+	"CronJob":     {"batch/v1", []string{"spec", "jobTemplate", "spec", "template", "spec"}},
+}
+
+// 单个工作负载的strategic-merge补丁
+type workloadPatch struct {
+	workloadKey
+	FileName string
+	Data     []byte
+}
+
+type kustomization struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Patches    []kustomizePatch `json:"patches"`
+}
+
+type kustomizePatch struct {
+	Path   string          `json:"path"`
+	Target kustomizeTarget `json:"target"`
+}
+
+type kustomizeTarget struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+func validatePatchFormat(format string) error {
+	switch format {
+	case "", patchStrategic, patchKustomize:
+		return nil
+	}
+	return fmt.Errorf("不支持的补丁格式: %s，可选 strategic|kustomize", format)
+}
+
+// 按工作负载汇总需要调整(过度分配或分配不足)的容器，生成补丁
+// 只修改结论不是ok的资源；建议值来自瞬时用量，allowLimitDecrease为false时只调高已有的limit，
+// 不调低也不新增limit，以免用量峰值触发OOM或限流
+func BuildPatches(recommendations []*Recommendation, allowLimitDecrease bool) ([]workloadPatch, error) {
+	var (
+		order      []workloadKey
+		containers = make(map[workloadKey][]interface{})
+	)
+	for _, r := range recommendations {
+		if r.CPUStatus == provisionOK && r.MemStatus == provisionOK {
+			continue
+		}
+		if !r.patchable() {
+			fmt.Fprintf(os.Stderr, "跳过 %s/%s/%s: 不支持为%s生成补丁\n", r.Namespace, r.Kind, r.Name, r.Kind)
+			continue
+		}
+
+		requests, limits := make(map[string]string), make(map[string]string)
+		if r.CPUStatus != provisionOK {
+			requests["cpu"] = cpuQuantity(r.Recommended.CPURequests)
+			if patchLimit(r.Current.CPULimits, r.Recommended.CPULimits, allowLimitDecrease) {
+				limits["cpu"] = cpuQuantity(r.Recommended.CPULimits)
+			}
+		}
+		if r.MemStatus != provisionOK {
+			requests["memory"] = memQuantity(r.Recommended.MemRequest)
+			if patchLimit(r.Current.MemLimits, r.Recommended.MemLimits, allowLimitDecrease) {
+				limits["memory"] = memQuantity(r.Recommended.MemLimits)
+			}
+		}
+		resources := map[string]interface{}{"requests": requests}
+		if len(limits) > 0 {
+			resources["limits"] = limits
+		}
+
+		if _, ok := containers[r.workloadKey]; !ok {
+			order = append(order, r.workloadKey)
+		}
+		containers[r.workloadKey] = append(containers[r.workloadKey], map[string]interface{}{
+			"name":      r.Container,
+			"resources": resources,
+		})
+	}
+
+	patches := make([]workloadPatch, 0, len(order))
+	for _, key := range order {
+		target := patchTargets[key.Kind]
+		var spec interface{} = map[string]interface{}{"containers": containers[key]}
+		for i := len(target.path) - 1; i >= 0; i-- {
+			spec = map[string]interface{}{target.path[i]: spec}
+		}
+		object := spec.(map[string]interface{})
+		object["apiVersion"] = target.apiVersion
+		object["kind"] = key.Kind
+		object["metadata"] = map[string]interface{}{"name": key.Name, "namespace": key.Namespace}
+
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, workloadPatch{
+			workloadKey: key,
+			FileName:    strings.ToLower(fmt.Sprintf("%s-%s-%s.yaml", key.Namespace, key.Kind, key.Name)),
+			Data:        data,
+		})
+	}
+	return patches, nil
+}
+
+// 未设置limit(为0)视为不限制，调低或新增limit都需要显式允许
+func patchLimit(current, recommended int64, allowDecrease bool) bool {
+	return allowDecrease || (current > 0 && recommended > current)
+}
+
+func cpuQuantity(milli int64) string {
+	return resource.NewMilliQuantity(milli, resource.DecimalSI).String()
+}
+
+func memQuantity(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
+
+// kustomize格式额外生成kustomization.yaml，dir为空时以多文档yaml输出到w
+func WritePatches(w io.Writer, dir, format string, patches []workloadPatch) error {
+	files := make([]workloadPatch, 0, len(patches)+1)
+	files = append(files, patches...)
+	if format == patchKustomize {
+		k := kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization", Patches: []kustomizePatch{}}
+		for _, patch := range patches {
+			k.Patches = append(k.Patches, kustomizePatch{
+				Path:   patch.FileName,
+				Target: kustomizeTarget{Kind: patch.Kind, Name: patch.Name, Namespace: patch.Namespace},
+			})
+		}
+		data, err := yaml.Marshal(k)
+		if err != nil {
+			return err
+		}
+		files = append(files, workloadPatch{FileName: "kustomization.yaml", Data: data})
+	}
+
+	if dir == "" {
+		var buf bytes.Buffer
+		for _, file := range files {
+			fmt.Fprintf(&buf, "---\n# %s\n", file.FileName)
+			buf.Write(file.Data)
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file.FileName)
+		if err := os.WriteFile(path, file.Data, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s 已写入\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildPatches(t *testing.T) {
+	recommendations := []*Recommendation{
+		{
+			workloadKey: workloadKey{"default", "CronJob", "backup"},
+			Container:   "backup",
+			Recommended: ContainerResource{CPURequests: 250, CPULimits: 500, MemRequest: 256 << 20, MemLimits: 512 << 20},
+			CPUStatus:   provisionOver,
+			MemStatus:   provisionOK,
+		},
+		// 无需调整
+		{workloadKey: workloadKey{"default", "Deployment", "web"}, Container: "app", CPUStatus: provisionOK, MemStatus: provisionOK},
+		// 裸pod不可修改
+		{workloadKey: workloadKey{"default", "Pod", "debug"}, Container: "debug", CPUStatus: provisionUnder, MemStatus: provisionOK},
+	}
+
+	patches, err := BuildPatches(recommendations, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("got %d patches, want 1", len(patches))
+	}
+
+	// 只修改过度分配的cpu，未设置的limit不新增
+	want := `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: default
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            resources:
+              requests:
+                cpu: 250m
+`
+	if got := string(patches[0].Data); got != want {
+		t.Errorf("patch =\n%s\nwant\n%s", got, want)
+	}
+
+	dir := t.TempDir()
+	var out strings.Builder
+	if err := WritePatches(&out, dir, patchKustomize, patches); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "- path: default-cronjob-backup.yaml") {
+		t.Errorf("kustomization.yaml =\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, patches[0].FileName)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBuildPatchesLimits(t *testing.T) {
+	r := &Recommendation{
+		workloadKey: workloadKey{"default", "Deployment", "web"},
+		Container:   "app",
+		Current:     ContainerResource{CPURequests: 100, CPULimits: 200, MemRequest: 1 << 30, MemLimits: 1 << 30},
+		Recommended: ContainerResource{CPURequests: 250, CPULimits: 500, MemRequest: 256 << 20, MemLimits: 512 << 20},
+		CPUStatus:   provisionUnder,
+		MemStatus:   provisionOver,
+	}
+
+	tests := []struct {
+		allowDecrease bool
+		contains      []string
+		excludes      []string
+	}{
+		// cpu limit调高，内存limit保持1Gi
+		{false, []string{"cpu: 500m", "memory: 256Mi"}, []string{"memory: 512Mi"}},
+		{true, []string{"cpu: 500m", "memory: 256Mi", "memory: 512Mi"}, nil},
+	}
+	for _, tt := range tests {
+		patches, err := BuildPatches([]*Recommendation{r}, tt.allowDecrease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := string(patches[0].Data)
+		for _, s := range tt.contains {
+			if !strings.Contains(data, s) {
+				t.Errorf("allowDecrease=%v: patch missing %q:\n%s", tt.allowDecrease, s, data)
+			}
+		}
+		for _, s := range tt.excludes {
+			if strings.Contains(data, s) {
+				t.Errorf("allowDecrease=%v: patch should not contain %q:\n%s", tt.allowDecrease, s, data)
+			}
+		}
+	}
+}
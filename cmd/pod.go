@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"os"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"errors"
+	"time"
 
 	"metrics.k8s.io/kube"
 
@@ -19,37 +21,102 @@ import (
 	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
+// 单个DaemonSet在表格中最多展示的pod数
+const daemonSetPodLimit = 10
+
 var (
 	podResourcesMutex sync.Mutex
 	podMetricsMutex   sync.Mutex
 
 	daemonsetPod    = []string{"nodelocaldns","calico-node", "kube-proxy", "nginx-proxy","docc-agent","promtail","csi-rbdplugin","huawei-csi-node","node-exporter","clearlog","filebeat-business"}
 	podHeader       = []string{"节点名称", "pod名称", "cpu request|limit|usage", "cpu用量/request占比", "cpu用量/limit占比", "内存 request|limit|usage", "内存用量/request占比", "内存用量/limit占比"}
+	namespaceHeader = "命名空间"
+	initHeader      = "init峰值 cpu|内存 request"
+	podShowInit     bool
 	containerHeader = []string{"运行节点", "pod名称", "容器名称", "cpu request|limit|usage", "cpu用量/request占比", "cpu用量/limit占比", "内存 request|limit|usage", "内存用量/request占比", "内存用量/limit占比"}
 )
 
 var podCmd = &cobra.Command{
 	Use:   "pod",
 	Short: "Print the usage of pod in namespace",
-	Run: func(cmd *cobra.Command, args []string) {
-		PrintResult(rootCmd.Context(), namespace, NamespacesList)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(outputFormat); err != nil {
+			return err
+		}
+		if err := validateWatch(); err != nil {
+			return err
+		}
+		if podGroupBy != "" && podGroupBy != groupByOwner {
+			return fmt.Errorf("不支持的分组方式: %s，可选 owner", podGroupBy)
+		}
+		if err := validateSampling(); err != nil {
+			return err
+		}
+		thresholds, err := parseFailIf("pod")
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), requestTimeout)
+		defer cancel()
+		ns, nslist, err := targetNamespace(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case watchMode && podGroupBy == groupByOwner:
+			return watchTable(cmd.Context(), workloadView(ns, nslist))
+		case watchMode:
+			return watchTable(cmd.Context(), podView(ns, nslist))
+		case sampleDuration > 0:
+			return PrintSampledResult(cmd.Context(), ns, nslist)
+		case podGroupBy == groupByOwner:
+			return PrintWorkloads(ctx, ns, nslist)
+		default:
+			return PrintResult(ctx, ns, nslist, thresholds)
+		}
 	},
 	Args:    cobra.NoArgs,
 	Aliases: []string{"po", "pods"},
 }
 
+// 标签及字段选择器，对应 -l/--selector 与 --field-selector
+type Selector struct {
+	Label string
+	Field string
+}
+
+// 生成核心资源的ListOptions
+func (s Selector) ListOptions() metav1.ListOptions {
+	return metav1.ListOptions{ResourceVersion: "0", LabelSelector: s.Label, FieldSelector: s.Field}
+}
+
+// 生成metrics资源的ListOptions。metrics API仅支持metadata字段选择，
+// 字段选择器只作用于核心资源列表，合并时以核心资源为准即可保持结果一致
+func (s Selector) MetricsListOptions() metav1.ListOptions {
+	return metav1.ListOptions{ResourceVersion: "0", LabelSelector: s.Label}
+}
+
 type ContainerResource struct {
 	Name        string
 	CPURequests int64
 	CPULimits   int64
 	MemRequest  int64
 	MemLimits   int64
+	Sidecar     bool // restartPolicy为Always的init容器，与业务容器同时运行
 }
 
 type PodResource struct {
-	NodeName   string // Pod所在的节点
-	PodName    string
-	Containers map[string]*ContainerResource // 容器级别的资源信息
+	NodeName       string // Pod所在的节点
+	Namespace      string
+	PodName        string
+	OwnerKind      string // 直接控制者(ownerReferences中controller=true)的类型，无控制者时为空
+	OwnerName      string
+	Containers     map[string]*ContainerResource // 容器级别的资源信息
+	InitContainers []*ContainerResource          // init容器(含sidecar)，保持定义顺序
+	Overhead       ContainerResource             // pod.Spec.Overhead，只有request
+	CreatedAt      time.Time
 }
 
 type ContainerMetrics struct {
@@ -80,13 +147,32 @@ type PodInfo struct {
 	MemUsageToLimitsRatio  float64
 }
 
-func LoadK8sResource(ctx context.Context, namespace string) map[string]PodResource {
+// 没有符合条件的pod，命令行中作为错误提示，API中返回空列表
+type noPodsError string
+
+func (e noPodsError) Error() string {
+	return string(e)
+}
+
+func LoadK8sResource(ctx context.Context, namespace string, selector Selector) (map[string]PodResource, error) {
 	PodResources := make(map[string]PodResource, 0)
-	podList, err := kube.GetK8sClient().CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{ResourceVersion: "0"})
-	kube.Error(err, fmt.Sprintf("列出命名空间 %s 下的Pod失败", namespace))
+	client, err := kube.GetK8sClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	podList, err := client.CoreV1().Pods(namespace).List(ctx, selector.ListOptions())
+	if err != nil {
+		return nil, kube.Error(err, fmt.Sprintf("列出命名空间 %s 下的Pod失败", namespace))
+	}
 
 	if len(podList.Items) == 0 {
-		kube.Error(fmt.Errorf(",命名空间%s下无pod", namespace), "请重新指定命名空间")
+		if selector != (Selector{}) {
+			return nil, noPodsError("未找到匹配选择器的pod")
+		}
+		if namespace == metav1.NamespaceAll {
+			return nil, noPodsError("集群中无pod")
+		}
+		return nil, noPodsError(fmt.Sprintf("命名空间%s下无pod，请重新指定命名空间", namespace))
 	}
 
 	var wg sync.WaitGroup
@@ -96,41 +182,116 @@ func LoadK8sResource(ctx context.Context, namespace string) map[string]PodResour
 		go func(pod corev1.Pod) {
 			defer wg.Done()
 
-			containersResource := make(map[string]*ContainerResource)
-
-			// Iterate over containers in the pod
-			for i := range pod.Spec.Containers {
-				container := pod.Spec.Containers[i]
-				containerResource := &ContainerResource{
-					Name:        container.Name,
-					CPURequests: container.Resources.Requests.Cpu().MilliValue(),
-					CPULimits:   container.Resources.Limits.Cpu().MilliValue(),
-					MemRequest:  container.Resources.Requests.Memory().Value(),
-					MemLimits:   container.Resources.Limits.Memory().Value(),
-				}
-				containersResource[container.Name] = containerResource
-			}
-
-			podResource := PodResource{
-				PodName:    pod.Name,
-				NodeName:   pod.Spec.NodeName,
-				Containers: containersResource,
-			}
+			podResource := newPodResource(pod)
 
 			podResourcesMutex.Lock()
-			PodResources[encode(namespace, podResource.PodName)] = podResource
+			PodResources[encode(pod.Namespace, podResource.PodName)] = podResource
 			podResourcesMutex.Unlock()
 		}(pod)
 	}
 
 	wg.Wait()
-	return PodResources
+	return PodResources, nil
+}
+
+// 提取pod及其容器的资源配置
+func newPodResource(pod corev1.Pod) PodResource {
+	podResource := PodResource{
+		Namespace:  pod.Namespace,
+		PodName:    pod.Name,
+		NodeName:   pod.Spec.NodeName,
+		CreatedAt:  pod.CreationTimestamp.Time,
+		Containers: make(map[string]*ContainerResource, len(pod.Spec.Containers)),
+		Overhead: ContainerResource{
+			CPURequests: pod.Spec.Overhead.Cpu().MilliValue(),
+			MemRequest:  pod.Spec.Overhead.Memory().Value(),
+		},
+	}
+	for _, container := range pod.Spec.Containers {
+		podResource.Containers[container.Name] = newContainerResource(container)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		initContainer := newContainerResource(container)
+		initContainer.Sidecar = container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
+		podResource.InitContainers = append(podResource.InitContainers, initContainer)
+	}
+	if owner := metav1.GetControllerOfNoCopy(&pod); owner != nil {
+		podResource.OwnerKind, podResource.OwnerName = owner.Kind, owner.Name
+	}
+	return podResource
+}
+
+func newContainerResource(container corev1.Container) *ContainerResource {
+	return &ContainerResource{
+		Name:        container.Name,
+		CPURequests: container.Resources.Requests.Cpu().MilliValue(),
+		CPULimits:   container.Resources.Limits.Cpu().MilliValue(),
+		MemRequest:  container.Resources.Requests.Memory().Value(),
+		MemLimits:   container.Resources.Limits.Memory().Value(),
+	}
+}
+
+func (r *ContainerResource) add(other *ContainerResource) {
+	r.CPURequests += other.CPURequests
+	r.CPULimits += other.CPULimits
+	r.MemRequest += other.MemRequest
+	r.MemLimits += other.MemLimits
+}
+
+// 各项分别取较大值
+func (r *ContainerResource) max(other *ContainerResource) {
+	r.CPURequests = maxInt64(r.CPURequests, other.CPURequests)
+	r.CPULimits = maxInt64(r.CPULimits, other.CPULimits)
+	r.MemRequest = maxInt64(r.MemRequest, other.MemRequest)
+	r.MemLimits = maxInt64(r.MemLimits, other.MemLimits)
 }
 
-func LoadK8sMetrics(ctx context.Context, namespace string) map[string]PodMetrics {
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// init阶段的峰值: 普通init容器依次运行，运行时需加上此前已启动的sidecar，取各步中的最大值
+// sidecars为所有sidecar之和
+func (pod PodResource) initPeak() (peak, sidecars ContainerResource) {
+	for _, container := range pod.InitContainers {
+		if container.Sidecar {
+			sidecars.add(container)
+			peak.max(&sidecars)
+			continue
+		}
+		step := sidecars
+		step.add(container)
+		peak.max(&step)
+	}
+	return peak, sidecars
+}
+
+// 与调度器一致的pod有效request/limit: 业务容器与sidecar之和、init阶段峰值取较大值，再加上overhead
+func (pod PodResource) effectiveResources() ContainerResource {
+	var total ContainerResource
+	for _, container := range pod.Containers {
+		total.add(container)
+	}
+	peak, sidecars := pod.initPeak()
+	total.add(&sidecars)
+	total.max(&peak)
+	total.add(&pod.Overhead)
+	return total
+}
+
+func LoadK8sMetrics(ctx context.Context, namespace string, selector Selector) (map[string]PodMetrics, error) {
 	PodsMetrics := make(map[string]PodMetrics, 0)
-	PodMetricsList, err := kube.GetMetricsClient().MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{ResourceVersion: "0"})
-	kube.Error(err, fmt.Sprintf("获取命名空间 %s 下pod的指标失败", namespace))
+	client, err := kube.GetMetricsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	PodMetricsList, err := client.MetricsV1beta1().PodMetricses(namespace).List(ctx, selector.MetricsListOptions())
+	if err != nil {
+		return nil, kube.MetricsError(err, fmt.Sprintf("获取命名空间 %s 下pod的指标失败", namespace))
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(len(PodMetricsList.Items))
@@ -157,13 +318,13 @@ func LoadK8sMetrics(ctx context.Context, namespace string) map[string]PodMetrics
 			}
 
 			podMetricsMutex.Lock()
-			PodsMetrics[encode(namespace, podMetricInfo.PodName)] = podMetricInfo
+			PodsMetrics[encode(podMetric.Namespace, podMetricInfo.PodName)] = podMetricInfo
 			podMetricsMutex.Unlock()
 		}(podMetric)
 	}
 
 	wg.Wait()
-	return PodsMetrics
+	return PodsMetrics, nil
 }
 
 // 合并上面的Podresource及PodMetrics信息到podInfoList中
@@ -205,21 +366,26 @@ func (podInfo *PodInfo) calculateContainerMetrics() {
 	}
 }
 
-func (podInfo *PodInfo) calculateTotalMetrics() {
-	totalCPUUsage, totalCPURequests, totalCPULimits := int64(0), int64(0), int64(0)
-	totalMemUsage, totalMemRequests, totalMemLimits := int64(0), int64(0), int64(0)
-
-	for _, containerResource := range podInfo.PodResource.Containers {
-		totalCPURequests += containerResource.CPURequests
-		totalCPULimits += containerResource.CPULimits
-		totalMemRequests += containerResource.MemRequest
-		totalMemLimits += containerResource.MemLimits
-	}
+// pod的有效request、limit(见effectiveResources)及所有容器的用量之和
+type podTotals struct {
+	CPURequests, CPULimits, CPUUsage int64
+	MemRequests, MemLimits, MemUsage int64
+}
 
+func (podInfo *PodInfo) totals() podTotals {
+	r := podInfo.PodResource.effectiveResources()
+	t := podTotals{CPURequests: r.CPURequests, CPULimits: r.CPULimits, MemRequests: r.MemRequest, MemLimits: r.MemLimits}
 	for _, containerMetric := range podInfo.PodMetrics.Containers {
-		totalCPUUsage += containerMetric.CPUUsage
-		totalMemUsage += containerMetric.MemUsage
+		t.CPUUsage += containerMetric.CPUUsage
+		t.MemUsage += containerMetric.MemUsage
 	}
+	return t
+}
+
+func (podInfo *PodInfo) calculateTotalMetrics() {
+	t := podInfo.totals()
+	totalCPUUsage, totalCPURequests, totalCPULimits := t.CPUUsage, t.CPURequests, t.CPULimits
+	totalMemUsage, totalMemRequests, totalMemLimits := t.MemUsage, t.MemRequests, t.MemLimits
 
 	podInfo.CPUUsageToRequestRatio = calculateRatio(totalCPUUsage, totalCPURequests)
 	podInfo.CPUUsageToLimitsRatio = calculateRatio(totalCPUUsage, totalCPULimits)
@@ -227,75 +393,203 @@ func (podInfo *PodInfo) calculateTotalMetrics() {
 	podInfo.MemUsageToLimitsRatio = calculateRatio(totalMemUsage, totalMemLimits)
 }
 
-func PrintResult(ctx context.Context, namespace string, nslist []string) {
-	if !IsNamespaceExist(namespace, nslist) {
-		kube.Error(errors.New(",该命名空间下无pod"), "请重新指定命名空间")
+// 输出pod信息，指定阈值时在输出后返回未通过的pod
+func PrintResult(ctx context.Context, namespace string, nslist []string, thresholds []threshold) error {
+	combinedPodInfoList, err := LoadPodInfo(ctx, namespace, nslist)
+	if err != nil {
+		return err
 	}
-	resources := LoadK8sResource(ctx, namespace)
-	metrics := LoadK8sMetrics(ctx, namespace)
 
-	combinedPodInfoList := SortPodInfo(CombinePodInfo(resources, metrics))
+	if isStructuredOutput(outputFormat) {
+		if err := writePodRecords(os.Stdout, outputFormat, combinedPodInfoList, podSortByContainer); err != nil {
+			return err
+		}
+	} else {
+		header, rows := podTableRows(combinedPodInfoList, namespace == metav1.NamespaceAll)
+		renderRows(os.Stdout, header, rows)
+	}
+	return thresholdError(podViolations(thresholds, combinedPodInfoList))
+}
 
-	podResults := make([][]string, 0)
+// 采集命名空间下pod的资源及用量并排序
+func LoadPodInfo(ctx context.Context, namespace string, nslist []string) ([]*PodInfo, error) {
+	combinedPodInfoList, err := loadCombinedPodInfo(ctx, namespace, nslist)
+	if err != nil {
+		return nil, err
+	}
+	return SortPodInfo(combinedPodInfoList), nil
+}
+
+// 采集命名空间下pod的资源及用量，不排序
+func loadCombinedPodInfo(ctx context.Context, namespace string, nslist []string) ([]*PodInfo, error) {
+	if namespace != metav1.NamespaceAll && !IsNamespaceExist(namespace, nslist) {
+		return nil, fmt.Errorf("%w: %s", kube.ErrNamespaceNotFound, namespace)
+	}
+	return loadSelectedPodInfo(ctx, namespace, podSelector)
+}
+
+// 按选择器采集pod的资源及用量，调用方负责确认命名空间存在
+func loadSelectedPodInfo(ctx context.Context, namespace string, selector Selector) ([]*PodInfo, error) {
+	resources, err := LoadK8sResource(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := LoadK8sMetrics(ctx, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return CombinePodInfo(resources, metrics), nil
+}
+
+// 生成pod表格，查询所有命名空间时在首列输出命名空间
+func podTableRows(combinedPodInfoList []*PodInfo, showNamespace bool) ([]string, []tableRow) {
+	if !showNamespace {
+		combinedPodInfoList = limitDaemonSetPods(combinedPodInfoList)
+	}
+	podResults := make([]tableRow, 0)
 	for _, podInfo := range combinedPodInfoList {
+		podKey := podInfo.PodResource.Namespace + "/" + podInfo.PodResource.PodName
 		if podSortByContainer {
 			// 输出容器级别的信息
-			for containerName, containerResource := range podInfo.PodResource.Containers {
-				containerMetric := podInfo.PodMetrics.Containers[containerName]
-				containerRatio := podInfo.ContainersRatio[containerName]
-				cpuUsage := formatResourceUsage(containerResource.CPURequests, containerResource.CPULimits, containerMetric.CPUUsage, "CPU")
-				memUsage := formatResourceUsage(containerResource.MemRequest, containerResource.MemLimits, containerMetric.MemUsage, "Memory")
-				result := []string{
-					podInfo.NodeName,
-					podInfo.PodResource.PodName,
-					containerName,
-					cpuUsage,
-					formatValue(containerRatio.CPUUsageToRequestRatio),
-					formatValue(containerRatio.CPUUsageToLimitsRatio),
-					memUsage,
-					formatValue(containerRatio.MemUsageToRequestRatio),
-					formatValue(containerRatio.MemUsageToLimitsRatio),
+			for _, row := range containerTableRows(podInfo) {
+				result := append([]string{podInfo.NodeName, podInfo.PodResource.PodName}, row.Cells...)
+				if showNamespace {
+					result = append([]string{podInfo.PodResource.Namespace}, result...)
 				}
-				podResults = append(podResults, result)
+				row.Cells = result
+				podResults = append(podResults, row)
 			}
 		} else {
 			// 输出Pod级别的信息
-			totalCPURequests, totalCPULimits, totalMemRequests, totalMemLimits := int64(0), int64(0), int64(0), int64(0)
-			totalCPUUsage, totalMemUsage := int64(0), int64(0)
-			for _, containerResource := range podInfo.PodResource.Containers {
-				totalCPURequests += containerResource.CPURequests
-				totalCPULimits += containerResource.CPULimits
-				totalMemRequests += containerResource.MemRequest
-				totalMemLimits += containerResource.MemLimits
-			}
-			for _, containerMetric := range podInfo.PodMetrics.Containers {
-				totalCPUUsage += containerMetric.CPUUsage
-				totalMemUsage += containerMetric.MemUsage
-			}
-			cpuUsage := formatResourceUsage(totalCPURequests, totalCPULimits, totalCPUUsage, "CPU")
-			memUsage := formatResourceUsage(totalMemRequests, totalMemLimits, totalMemUsage, "Memory")
+			t := podInfo.totals()
+			cpuUsage := formatResourceUsage(t.CPURequests, t.CPULimits, t.CPUUsage, "CPU")
+			memUsage := formatResourceUsage(t.MemRequests, t.MemLimits, t.MemUsage, "Memory")
 			result := []string{
 				podInfo.NodeName,
 				podInfo.PodResource.PodName,
 				cpuUsage,
-				formatValue(podInfo.CPUUsageToRequestRatio),
-				formatValue(podInfo.CPUUsageToLimitsRatio),
+				ratioCell("pod.cpu.request", podInfo.CPUUsageToRequestRatio),
+				ratioCell("pod.cpu.limit", podInfo.CPUUsageToLimitsRatio),
 				memUsage,
-				formatValue(podInfo.MemUsageToRequestRatio),
-				formatValue(podInfo.MemUsageToLimitsRatio),
+				ratioCell("pod.mem.request", podInfo.MemUsageToRequestRatio),
+				ratioCell("pod.mem.limit", podInfo.MemUsageToLimitsRatio),
 			}
-			podResults = append(podResults, result)
+			if podShowInit {
+				peak, _ := podInfo.PodResource.initPeak()
+				result = append(result, formatRequests(peak.CPURequests, peak.MemRequest))
+			}
+			if showNamespace {
+				result = append([]string{podInfo.PodResource.Namespace}, result...)
+			}
+			podResults = append(podResults, tableRow{
+				Key:    podKey,
+				Ratios: []float64{podInfo.CPUUsageToRequestRatio, podInfo.CPUUsageToLimitsRatio, podInfo.MemUsageToRequestRatio, podInfo.MemUsageToLimitsRatio},
+				Cells:  result,
+			})
 		}
 	}
 
-	table := kube.NewTable()
+	header := podHeader
 	if podSortByContainer {
-		table.SetHeader(containerHeader)
-	} else {
-		table.SetHeader(podHeader)
+		header = containerHeader
+	} else if podShowInit {
+		header = append(append([]string(nil), podHeader...), initHeader)
+	}
+	if showNamespace {
+		header = append([]string{namespaceHeader}, header...)
+	}
+	return header, podResults
+}
+
+// pod内各容器的表格行，首列为容器名称，容器缺少metrics时用量按0处理
+func containerTableRows(podInfo *PodInfo) []tableRow {
+	podKey := podInfo.PodResource.Namespace + "/" + podInfo.PodResource.PodName
+	rows := make([]tableRow, 0, len(podInfo.PodResource.Containers))
+	for containerName, containerResource := range podInfo.PodResource.Containers {
+		containerMetric, ok := podInfo.PodMetrics.Containers[containerName]
+		if !ok {
+			containerMetric = &ContainerMetrics{Name: containerName}
+		}
+		containerRatio, ok := podInfo.ContainersRatio[containerName]
+		if !ok {
+			containerRatio = &ContainerRatio{}
+		}
+		cpuUsage := formatResourceUsage(containerResource.CPURequests, containerResource.CPULimits, containerMetric.CPUUsage, "CPU")
+		memUsage := formatResourceUsage(containerResource.MemRequest, containerResource.MemLimits, containerMetric.MemUsage, "Memory")
+		rows = append(rows, tableRow{
+			Key:    podKey + "/" + containerName,
+			Ratios: []float64{containerRatio.CPUUsageToRequestRatio, containerRatio.CPUUsageToLimitsRatio, containerRatio.MemUsageToRequestRatio, containerRatio.MemUsageToLimitsRatio},
+			Cells: []string{
+				containerName,
+				cpuUsage,
+				ratioCell("pod.cpu.request", containerRatio.CPUUsageToRequestRatio),
+				ratioCell("pod.cpu.limit", containerRatio.CPUUsageToLimitsRatio),
+				memUsage,
+				ratioCell("pod.mem.request", containerRatio.MemUsageToRequestRatio),
+				ratioCell("pod.mem.limit", containerRatio.MemUsageToLimitsRatio),
+			},
+		})
+	}
+	if podShowInit {
+		rows = append(rows, initContainerRows(podInfo)...)
+	}
+	return rows
+}
+
+// init容器的表格行，容器名称后标注(init)或(sidecar)，已结束的init容器没有用量
+func initContainerRows(podInfo *PodInfo) []tableRow {
+	podKey := podInfo.PodResource.Namespace + "/" + podInfo.PodResource.PodName
+	rows := make([]tableRow, 0, len(podInfo.PodResource.InitContainers))
+	for _, containerResource := range podInfo.PodResource.InitContainers {
+		containerMetric, ok := podInfo.PodMetrics.Containers[containerResource.Name]
+		if !ok {
+			containerMetric = &ContainerMetrics{Name: containerResource.Name}
+		}
+		kind := "init"
+		if containerResource.Sidecar {
+			kind = "sidecar"
+		}
+		ratios := []float64{
+			calculateRatio(containerMetric.CPUUsage, containerResource.CPURequests),
+			calculateRatio(containerMetric.CPUUsage, containerResource.CPULimits),
+			calculateRatio(containerMetric.MemUsage, containerResource.MemRequest),
+			calculateRatio(containerMetric.MemUsage, containerResource.MemLimits),
+		}
+		rows = append(rows, tableRow{
+			Key:    podKey + "/" + containerResource.Name,
+			Ratios: ratios,
+			Cells: []string{
+				fmt.Sprintf("%s (%s)", containerResource.Name, kind),
+				formatResourceUsage(containerResource.CPURequests, containerResource.CPULimits, containerMetric.CPUUsage, "CPU"),
+				ratioCell("pod.cpu.request", ratios[0]),
+				ratioCell("pod.cpu.limit", ratios[1]),
+				formatResourceUsage(containerResource.MemRequest, containerResource.MemLimits, containerMetric.MemUsage, "Memory"),
+				ratioCell("pod.mem.request", ratios[2]),
+				ratioCell("pod.mem.limit", ratios[3]),
+			},
+		})
+	}
+	return rows
+}
+
+func formatRequests(cpu, memory int64) string {
+	if cpu == 0 && memory == 0 {
+		return "-"
+	}
+	return convertToUnits(cpu, "CPU") + "|" + convertToUnits(memory, "Memory")
+}
+
+// watch模式下周期刷新的pod视图
+func podView(namespace string, nslist []string) tableView {
+	return func(ctx context.Context) ([]string, []tableRow, error) {
+		combinedPodInfoList, err := LoadPodInfo(ctx, namespace, nslist)
+		if err != nil {
+			return nil, nil, err
+		}
+		header, rows := podTableRows(combinedPodInfoList, namespace == metav1.NamespaceAll)
+		return header, rows, nil
 	}
-	table.AppendBulk(podResults)
-	table.Render()
 }
 
 func formatResourceUsage(request, limit, usage int64, resourceType string) string {
@@ -342,28 +636,29 @@ func encode(ns, pod string) string {
 }
 
 func SortPodInfo(combinedPodInfoList []*PodInfo) []*PodInfo {
+	return sortPodInfo(combinedPodInfoList, podSortBy, podSortByContainer)
+}
+
+func sortPodInfo(combinedPodInfoList []*PodInfo, sortBy string, byContainer bool) []*PodInfo {
 	// 对combinedPodInfoList进行排序
 	sort.Slice(combinedPodInfoList, func(i, j int) bool {
-		podNamePartsI := strings.Split(combinedPodInfoList[i].PodResource.PodName, "-")
-		podNamePartsJ := strings.Split(combinedPodInfoList[j].PodResource.PodName, "-")
-
-		// 取前两位进行分组
-		groupI := strings.Join(podNamePartsI[:2], "-")
-		groupJ := strings.Join(podNamePartsJ[:2], "-")
+		// 按命名空间及直接控制者进行分组
+		groupI := combinedPodInfoList[i].PodResource.group()
+		groupJ := combinedPodInfoList[j].PodResource.group()
 
 		if groupI != groupJ {
 			return groupI < groupJ
 		}
 
 		// 根据命令行选项来判断是否进行容器级别排序
-		if podSortByContainer {
+		if byContainer {
 			return containerSortLess(combinedPodInfoList[i].PodResource.Containers, combinedPodInfoList[j].PodResource.Containers, func(container *ContainerResource) int64 {
 				return container.CPURequests
 			})
 		}
 
 		// 根据排序规则进行排序
-		switch podSortBy {
+		switch sortBy {
 		case "cpu.request":
 			return combinedPodInfoList[i].CPUUsageToRequestRatio < combinedPodInfoList[j].CPUUsageToRequestRatio
 		case "mem.request":
@@ -377,14 +672,26 @@ func SortPodInfo(combinedPodInfoList []*PodInfo) []*PodInfo {
 		}
 	})
 
-	// 如果Pod是DaemonSet类型，只保留前10个PodInfo
-	if isDaemonSetPod(combinedPodInfoList[0].PodResource.PodName) && len(combinedPodInfoList) > 10 {
-		combinedPodInfoList = combinedPodInfoList[:10]
-	}
-
 	return combinedPodInfoList
 }
 
+// DaemonSet在每个节点上都有一个pod，单个命名空间的表格中每个DaemonSet只展示排序后的前daemonSetPodLimit个
+// 只用于表格展示，-A、结构化输出、阈值检查、分组汇总及API均使用完整的列表
+func limitDaemonSetPods(combinedPodInfoList []*PodInfo) []*PodInfo {
+	limited := make([]*PodInfo, 0, len(combinedPodInfoList))
+	counts := make(map[string]int)
+	for _, podInfo := range combinedPodInfoList {
+		if group, ok := daemonSetGroup(podInfo.PodResource); ok {
+			if counts[group] >= daemonSetPodLimit {
+				continue
+			}
+			counts[group]++
+		}
+		limited = append(limited, podInfo)
+	}
+	return limited
+}
+
 func containerSortLess(containersA, containersB map[string]*ContainerResource, getMetric func(*ContainerResource) int64) bool {
 	// Convert map to slice for sorting
 	sliceA := make([]*ContainerResource, 0, len(containersA))
@@ -405,14 +712,25 @@ func containerSortLess(containersA, containersB map[string]*ContainerResource, g
 	return len(sliceA) < len(sliceB)
 }
 
-// 判断是否为StatefulSet类型的Pod
-func isDaemonSetPod(podName string) bool {
+// 返回DaemonSet类型pod所属的分组，daemonsetPod用于兼容未设置ownerReferences的静态pod等，按匹配的关键字分组
+func daemonSetGroup(pod PodResource) (string, bool) {
+	if pod.OwnerKind == "DaemonSet" {
+		return pod.group(), true
+	}
 	for _, keyword := range daemonsetPod {
-		if strings.Contains(podName, keyword) {
-			return true
+		if strings.Contains(pod.PodName, keyword) {
+			return pod.Namespace + "/DaemonSet/" + keyword, true
 		}
 	}
-	return false
+	return "", false
+}
+
+// pod的分组键，同一控制者下的pod归为一组，无控制者的pod单独成组
+func (pod PodResource) group() string {
+	if pod.OwnerKind == "" {
+		return pod.Namespace + "/Pod/" + pod.PodName
+	}
+	return pod.Namespace + "/" + pod.OwnerKind + "/" + pod.OwnerName
 }
 
 // 计算usage和request/limit之间的比例
@@ -442,14 +760,36 @@ func formatValue(val interface{}) string {
 }
 
 // 列出所有的namespace
-func ListNamespace(ctx context.Context) []string {
-	nsList, err := kube.GetK8sClient().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
-	kube.Error(err, "列出命名空间失败")
+func ListNamespace(ctx context.Context) ([]string, error) {
+	client, err := kube.GetK8sClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nsList, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, kube.Error(err, "列出命名空间失败")
+	}
 
+	namespaces := make([]string, 0, len(nsList.Items))
 	for _, ns := range nsList.Items {
-		NamespacesList = append(NamespacesList, ns.Name)
+		namespaces = append(namespaces, ns.Name)
+	}
+	return namespaces, nil
+}
+
+// 根据 -n/-A 返回要查询的命名空间，-A 时为空字符串且不需要命名空间列表
+func targetNamespace(ctx context.Context) (string, []string, error) {
+	if allNamespaces {
+		return metav1.NamespaceAll, nil, nil
+	}
+	if namespace == "" {
+		return "", nil, errors.New("请通过 -n 指定命名空间，或使用 -A 查询所有命名空间")
+	}
+	nslist, err := ListNamespace(ctx)
+	if err != nil {
+		return "", nil, err
 	}
-	return NamespacesList
+	return namespace, nslist, nil
 }
 
 // 判断是否存在指定的namespace
@@ -0,0 +1,392 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"metrics.k8s.io/kube"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func fakeContext(t *testing.T, objects []runtime.Object, podMetrics []metricsv1beta1.PodMetrics, nodeMetrics []metricsv1beta1.NodeMetrics) context.Context {
+	t.Helper()
+
+	clients, err := kube.NewFakeClients(objects, podMetrics, nodeMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kube.WithClients(context.Background(), clients)
+}
+
+func resourceList(cpu, mem string) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	if cpu != "" {
+		list[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if mem != "" {
+		list[corev1.ResourceMemory] = resource.MustParse(mem)
+	}
+	return list
+}
+
+func newPod(ns, name, node string, containers ...corev1.Container) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec:       corev1.PodSpec{NodeName: node, Containers: containers},
+	}
+}
+
+func newContainer(name string, requests, limits corev1.ResourceList) corev1.Container {
+	return corev1.Container{
+		Name:      name,
+		Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits},
+	}
+}
+
+func newPodMetrics(ns, name string, usage map[string]corev1.ResourceList) metricsv1beta1.PodMetrics {
+	podMetrics := metricsv1beta1.PodMetrics{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	for container, list := range usage {
+		podMetrics.Containers = append(podMetrics.Containers, metricsv1beta1.ContainerMetrics{Name: container, Usage: list})
+	}
+	return podMetrics
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func TestCombinePodInfoRatios(t *testing.T) {
+	tests := []struct {
+		name       string
+		pod        *corev1.Pod
+		usage      map[string]corev1.ResourceList
+		wantPod    ContainerRatio
+		wantRatios map[string]ContainerRatio
+	}{
+		{
+			name: "single container",
+			pod: newPod("default", "web-app-1", "node-a",
+				newContainer("app", resourceList("100m", "100M"), resourceList("200m", "200M"))),
+			usage:      map[string]corev1.ResourceList{"app": resourceList("50m", "50M")},
+			wantPod:    ContainerRatio{50, 25, 50, 25},
+			wantRatios: map[string]ContainerRatio{"app": {50, 25, 50, 25}},
+		},
+		{
+			name: "multiple containers are summed",
+			pod: newPod("default", "web-app-2", "node-a",
+				newContainer("app", resourceList("300m", "300M"), resourceList("600m", "600M")),
+				newContainer("sidecar", resourceList("100m", "100M"), resourceList("200m", "200M"))),
+			usage: map[string]corev1.ResourceList{
+				"app":     resourceList("300m", "150M"),
+				"sidecar": resourceList("100m", "50M"),
+			},
+			wantPod: ContainerRatio{100, 50, 50, 25},
+			wantRatios: map[string]ContainerRatio{
+				"app":     {100, 50, 50, 25},
+				"sidecar": {100, 50, 50, 25},
+			},
+		},
+		{
+			name: "missing limits yield zero ratio",
+			pod: newPod("default", "web-app-3", "node-b",
+				newContainer("app", resourceList("200m", "100M"), nil)),
+			usage:      map[string]corev1.ResourceList{"app": resourceList("100m", "200M")},
+			wantPod:    ContainerRatio{50, 0, 200, 0},
+			wantRatios: map[string]ContainerRatio{"app": {50, 0, 200, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := fakeContext(t,
+				[]runtime.Object{tt.pod},
+				[]metricsv1beta1.PodMetrics{newPodMetrics(tt.pod.Namespace, tt.pod.Name, tt.usage)},
+				nil)
+
+			podInfoList := loadPodInfo(t, ctx, "default")
+			if len(podInfoList) != 1 {
+				t.Fatalf("got %d pods, want 1", len(podInfoList))
+			}
+
+			podInfo := podInfoList[0]
+			got := ContainerRatio{podInfo.CPUUsageToRequestRatio, podInfo.CPUUsageToLimitsRatio, podInfo.MemUsageToRequestRatio, podInfo.MemUsageToLimitsRatio}
+			if !ratioEqual(got, tt.wantPod) {
+				t.Errorf("pod ratio = %+v, want %+v", got, tt.wantPod)
+			}
+			for name, want := range tt.wantRatios {
+				ratio, ok := podInfo.ContainersRatio[name]
+				if !ok {
+					t.Fatalf("missing ratio for container %s", name)
+				}
+				if !ratioEqual(*ratio, want) {
+					t.Errorf("container %s ratio = %+v, want %+v", name, *ratio, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCombinePodInfoSkipsPodsWithoutMetrics(t *testing.T) {
+	ctx := fakeContext(t,
+		[]runtime.Object{
+			newPod("default", "web-app-1", "node-a", newContainer("app", resourceList("100m", "100M"), nil)),
+			newPod("default", "web-app-2", "node-a", newContainer("app", resourceList("100m", "100M"), nil)),
+		},
+		[]metricsv1beta1.PodMetrics{
+			newPodMetrics("default", "web-app-1", map[string]corev1.ResourceList{"app": resourceList("10m", "10M")}),
+		},
+		nil)
+
+	podInfoList := loadPodInfo(t, ctx, "default")
+	if len(podInfoList) != 1 || podInfoList[0].PodResource.PodName != "web-app-1" {
+		t.Fatalf("got %d pods, want only web-app-1", len(podInfoList))
+	}
+}
+
+func TestCombinePodInfoAllNamespaces(t *testing.T) {
+	ctx := fakeContext(t,
+		[]runtime.Object{
+			newPod("team-a", "web-app-1", "node-a", newContainer("app", resourceList("100m", "100M"), nil)),
+			newPod("team-b", "web-app-1", "node-b", newContainer("app", resourceList("100m", "100M"), nil)),
+		},
+		[]metricsv1beta1.PodMetrics{
+			newPodMetrics("team-a", "web-app-1", map[string]corev1.ResourceList{"app": resourceList("10m", "10M")}),
+			newPodMetrics("team-b", "web-app-1", map[string]corev1.ResourceList{"app": resourceList("80m", "80M")}),
+		},
+		nil)
+
+	want := map[string]float64{"team-a": 10, "team-b": 80}
+	podInfoList := loadPodInfo(t, ctx, metav1.NamespaceAll)
+	if len(podInfoList) != len(want) {
+		t.Fatalf("got %d pods, want %d", len(podInfoList), len(want))
+	}
+	for _, podInfo := range podInfoList {
+		if !almostEqual(podInfo.CPUUsageToRequestRatio, want[podInfo.PodResource.Namespace]) {
+			t.Errorf("%s/%s cpu ratio = %.2f, want %.2f", podInfo.PodResource.Namespace, podInfo.PodResource.PodName, podInfo.CPUUsageToRequestRatio, want[podInfo.PodResource.Namespace])
+		}
+	}
+}
+
+func TestLoadWithLabelSelector(t *testing.T) {
+	payments := newPod("default", "payments-api-1", "node-a", newContainer("app", resourceList("100m", "100M"), nil))
+	payments.Labels = map[string]string{"app": "payments"}
+	// metrics-server会将pod的标签复制到PodMetrics上
+	paymentsMetrics := newPodMetrics("default", "payments-api-1", map[string]corev1.ResourceList{"app": resourceList("10m", "10M")})
+	paymentsMetrics.Labels = payments.Labels
+	ctx := fakeContext(t,
+		[]runtime.Object{
+			payments,
+			newPod("default", "web-app-1", "node-a", newContainer("app", resourceList("100m", "100M"), nil)),
+		},
+		[]metricsv1beta1.PodMetrics{
+			paymentsMetrics,
+			newPodMetrics("default", "web-app-1", map[string]corev1.ResourceList{"app": resourceList("10m", "10M")}),
+		},
+		nil)
+
+	selector := Selector{Label: "app=payments"}
+	resources, err := LoadK8sResource(ctx, "default", selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := LoadK8sMetrics(ctx, "default", selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podInfoList := CombinePodInfo(resources, metrics)
+	if len(resources) != 1 || len(podInfoList) != 1 || podInfoList[0].PodResource.PodName != "payments-api-1" {
+		t.Fatalf("got %d resources and %d pods, want only payments-api-1", len(resources), len(podInfoList))
+	}
+
+	if _, err := LoadK8sResource(ctx, "default", Selector{Label: "app=missing"}); err == nil {
+		t.Error("expected error when selector matches no pods")
+	}
+}
+
+func TestSortPodInfo(t *testing.T) {
+	podInfo := func(name, owner string, cpuReq, memReq, cpuLim, memLim float64) *PodInfo {
+		return &PodInfo{
+			PodResource:            PodResource{Namespace: "default", PodName: name, OwnerKind: "ReplicaSet", OwnerName: owner},
+			CPUUsageToRequestRatio: cpuReq,
+			MemUsageToRequestRatio: memReq,
+			CPUUsageToLimitsRatio:  cpuLim,
+			MemUsageToLimitsRatio:  memLim,
+		}
+	}
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{"cpu.request", []string{"standalone", "api-server-b", "api-server-a", "web-app-b", "web-app-a"}},
+		{"mem.request", []string{"standalone", "api-server-a", "api-server-b", "web-app-a", "web-app-b"}},
+		{"cpu.limit", []string{"standalone", "api-server-a", "api-server-b", "web-app-b", "web-app-a"}},
+		{"mem.limit", []string{"standalone", "api-server-b", "api-server-a", "web-app-a", "web-app-b"}},
+	}
+
+	defer func(sortBy string, byContainer bool) {
+		podSortBy, podSortByContainer = sortBy, byContainer
+	}(podSortBy, podSortByContainer)
+	podSortByContainer = false
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			podSortBy = tt.sortBy
+			// 按控制者分组，名称中不含"-"的pod也不应panic
+			standalone := podInfo("standalone", "", 1, 1, 1, 1)
+			standalone.PodResource.OwnerKind = ""
+			list := []*PodInfo{
+				podInfo("web-app-a", "web-app-7d9f", 90, 10, 50, 10),
+				standalone,
+				podInfo("api-server-a", "api-server-5c4b", 60, 10, 10, 80),
+				podInfo("web-app-b", "web-app-7d9f", 40, 20, 30, 20),
+				podInfo("api-server-b", "api-server-5c4b", 30, 20, 20, 70),
+			}
+
+			sorted := SortPodInfo(list)
+			for i, name := range tt.want {
+				if sorted[i].PodResource.PodName != name {
+					t.Fatalf("position %d = %s, want %s", i, sorted[i].PodResource.PodName, name)
+				}
+			}
+		})
+	}
+}
+
+func TestLimitDaemonSetPods(t *testing.T) {
+	pod := func(name, kind, owner string) *PodInfo {
+		return &PodInfo{PodResource: PodResource{Namespace: "default", PodName: name, OwnerKind: kind, OwnerName: owner}}
+	}
+	defer func(sortBy string, byContainer bool) {
+		podSortBy, podSortByContainer = sortBy, byContainer
+	}(podSortBy, podSortByContainer)
+	podSortBy, podSortByContainer = "cpu.request", false
+
+	// DaemonSet分组排在Deployment之前，也不能截断其他pod
+	list := []*PodInfo{pod("log-agent-x1", "DaemonSet", "log-agent")}
+	for i := 0; i < 20; i++ {
+		list = append(list, pod(fmt.Sprintf("web-%02d", i), "ReplicaSet", "web-7d9f"))
+	}
+	if got := SortPodInfo(list); len(got) != 21 {
+		t.Fatalf("got %d pods, want 21", len(got))
+	}
+
+	// 每个DaemonSet只保留前daemonSetPodLimit个pod
+	list = list[1:]
+	for i := 0; i < 15; i++ {
+		list = append(list, pod(fmt.Sprintf("log-agent-%02d", i), "DaemonSet", "log-agent"), pod(fmt.Sprintf("kube-proxy-node%02d", i), "Node", fmt.Sprintf("node%02d", i)))
+	}
+	counts := make(map[string]int)
+	for _, podInfo := range limitDaemonSetPods(SortPodInfo(list)) {
+		counts[podInfo.PodResource.OwnerKind]++
+	}
+	if counts["ReplicaSet"] != 20 || counts["DaemonSet"] != daemonSetPodLimit || counts["Node"] != daemonSetPodLimit {
+		t.Errorf("pods per kind = %v, want 20 ReplicaSet and %d of each DaemonSet", counts, daemonSetPodLimit)
+	}
+
+	// 只截断单个命名空间的表格，-A及结构化输出使用完整列表
+	if got := len(SortPodInfo(list)); got != len(list) {
+		t.Errorf("SortPodInfo returned %d pods, want %d", got, len(list))
+	}
+	if _, rows := podTableRows(list, false); len(rows) != 20+2*daemonSetPodLimit {
+		t.Errorf("table rows = %d, want %d", len(rows), 20+2*daemonSetPodLimit)
+	}
+	if _, rows := podTableRows(list, true); len(rows) != len(list) {
+		t.Errorf("table rows with -A = %d, want %d", len(rows), len(list))
+	}
+}
+
+func TestCollectionErrorExitCodes(t *testing.T) {
+	podGR := schema.GroupResource{Resource: "pods"}
+	metricsGR := schema.GroupResource{Group: "metrics.k8s.io", Resource: "pods"}
+
+	tests := []struct {
+		name       string
+		podErr     error
+		metricsErr error
+		nslist     []string
+		want       int
+	}{
+		{name: "forbidden", podErr: apierrors.NewForbidden(podGR, "", nil), nslist: []string{"default"}, want: kube.ExitForbidden},
+		{name: "metrics not registered", metricsErr: apierrors.NewNotFound(metricsGR, ""), nslist: []string{"default"}, want: kube.ExitMetricsUnavailable},
+		{name: "metrics unavailable", metricsErr: apierrors.NewServiceUnavailable("metrics-server"), nslist: []string{"default"}, want: kube.ExitMetricsUnavailable},
+		{name: "namespace not found", nslist: []string{"kube-system"}, want: kube.ExitNamespaceNotFound},
+		{name: "other error", podErr: apierrors.NewInternalError(errors.New("boom")), nslist: []string{"default"}, want: kube.ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := fake.NewSimpleClientset(newPod("default", "web-app-1", "node-a", newContainer("app", resourceList("100m", "100M"), nil)))
+			if tt.podErr != nil {
+				k8sClient.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.podErr
+				})
+			}
+			metricsClient := metricsfake.NewSimpleClientset()
+			if tt.metricsErr != nil {
+				metricsClient.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.metricsErr
+				})
+			}
+			ctx := kube.WithClients(context.Background(), kube.Clients{K8s: k8sClient, Metrics: metricsClient})
+
+			err := PrintResult(ctx, "default", tt.nslist, nil)
+			if got := kube.ExitCode(err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func loadPodInfo(t *testing.T, ctx context.Context, namespace string) []*PodInfo {
+	t.Helper()
+
+	resources, err := LoadK8sResource(ctx, namespace, Selector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := LoadK8sMetrics(ctx, namespace, Selector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return CombinePodInfo(resources, metrics)
+}
+
+func ratioEqual(a, b ContainerRatio) bool {
+	return almostEqual(a.CPUUsageToRequestRatio, b.CPUUsageToRequestRatio) &&
+		almostEqual(a.CPUUsageToLimitsRatio, b.CPUUsageToLimitsRatio) &&
+		almostEqual(a.MemUsageToRequestRatio, b.MemUsageToRequestRatio) &&
+		almostEqual(a.MemUsageToLimitsRatio, b.MemUsageToLimitsRatio)
+}
+
+func TestListNamespace(t *testing.T) {
+	ctx := fakeContext(t, []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments"}},
+	}, nil, nil)
+
+	// 多次调用(如补全和命令本身)不能累积上一次的结果
+	for i := 0; i < 2; i++ {
+		namespaces, err := ListNamespace(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(namespaces) != 2 {
+			t.Errorf("call %d: got %v, want 2 namespaces", i+1, namespaces)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	recommendOpts   recommendOptions
+	recommendHeader = []string{"类型", "名称", "容器", "副本数", "cpu request|limit|usage", "建议cpu request|limit", "内存 request|limit|usage", "建议内存 request|limit", "结论"}
+)
+
+var recommendCmd = &cobra.Command{
+	Use:   "recommend",
+	Short: "Recommend container requests and limits from observed usage",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(outputFormat); err != nil {
+			return err
+		}
+		if err := validatePatchFormat(patchFormat); err != nil {
+			return err
+		}
+		policy, err := recommendOpts.policy()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), requestTimeout)
+		defer cancel()
+		ns, nslist, err := targetNamespace(ctx)
+		if err != nil {
+			return err
+		}
+		recommendations, err := LoadRecommendations(ctx, ns, nslist, policy)
+		if err != nil {
+			return err
+		}
+		if patchFormat != "" {
+			patches, err := BuildPatches(recommendations, recommendOpts.AllowLimitDecrease)
+			if err != nil {
+				return err
+			}
+			return WritePatches(os.Stdout, patchDir, patchFormat, patches)
+		}
+		if isStructuredOutput(outputFormat) {
+			return writeRecommendationRecords(os.Stdout, outputFormat, recommendations)
+		}
+		PrintRecommendations(os.Stdout, recommendations, ns == metav1.NamespaceAll)
+		return nil
+	},
+	Args:    cobra.NoArgs,
+	Aliases: []string{"rec"},
+}
+
+// 命令行传入的推荐参数，百分比取值0-100
+type recommendOptions struct {
+	Headroom       float64 // request在用量基础上预留的余量
+	LimitHeadroom  float64 // limit在用量基础上预留的余量
+	CPUStep        string  // 建议值向上取整的粒度，如10m
+	MemStep        string  // 如16Mi
+	OverThreshold  float64 // 用量/request低于该值视为过度分配
+	UnderThreshold float64 // 用量/request高于该值视为分配不足
+
+	AllowLimitDecrease bool // 补丁中允许调低或新增limit
+}
+
+// 解析后的推荐策略，CPU单位为毫核，内存单位为字节
+type recommendPolicy struct {
+	headroom       float64
+	limitHeadroom  float64
+	cpuStep        int64
+	memStep        int64
+	overThreshold  float64
+	underThreshold float64
+}
+
+func (o recommendOptions) policy() (recommendPolicy, error) {
+	if o.Headroom < 0 || o.LimitHeadroom < o.Headroom {
+		return recommendPolicy{}, fmt.Errorf("--headroom 不能小于0且 --limit-headroom 不能小于 --headroom: %.0f/%.0f", o.Headroom, o.LimitHeadroom)
+	}
+	if o.OverThreshold >= o.UnderThreshold {
+		return recommendPolicy{}, fmt.Errorf("--over-threshold 必须小于 --under-threshold: %.0f/%.0f", o.OverThreshold, o.UnderThreshold)
+	}
+	cpuStep, err := resource.ParseQuantity(o.CPUStep)
+	if err != nil {
+		return recommendPolicy{}, fmt.Errorf("无法解析 --cpu-step %q: %w", o.CPUStep, err)
+	}
+	memStep, err := resource.ParseQuantity(o.MemStep)
+	if err != nil {
+		return recommendPolicy{}, fmt.Errorf("无法解析 --mem-step %q: %w", o.MemStep, err)
+	}
+	return recommendPolicy{
+		headroom:       o.Headroom,
+		limitHeadroom:  o.LimitHeadroom,
+		cpuStep:        cpuStep.MilliValue(),
+		memStep:        memStep.Value(),
+		overThreshold:  o.OverThreshold,
+		underThreshold: o.UnderThreshold,
+	}, nil
+}
+
+// 资源分配结论
+type provisionStatus string
+
+const (
+	provisionOK    provisionStatus = "ok"
+	provisionOver  provisionStatus = "over"  // 申请远大于用量
+	provisionUnder provisionStatus = "under" // 用量超过申请或未设置request
+)
+
+// 工作负载内单个容器的推荐值，同一工作负载的副本共用一份spec
+type Recommendation struct {
+	workloadKey
+	Container   string
+	Replicas    int
+	Current     ContainerResource
+	CPUUsage    int64 // 各副本中的最大用量
+	MemUsage    int64
+	Recommended ContainerResource
+	CPUStatus   provisionStatus
+	MemStatus   provisionStatus
+}
+
+// 与补丁的范围一致，只统计可打补丁的工作负载中结论不是ok的资源
+// 按建议值调整后释放的request总量，为负时表示需要额外申请
+func (r *Recommendation) freed() (cpu, mem int64) {
+	if !r.patchable() {
+		return 0, 0
+	}
+	replicas := int64(r.Replicas)
+	if r.CPUStatus != provisionOK {
+		cpu = replicas * (r.Current.CPURequests - r.Recommended.CPURequests)
+	}
+	if r.MemStatus != provisionOK {
+		mem = replicas * (r.Current.MemRequest - r.Recommended.MemRequest)
+	}
+	return cpu, mem
+}
+
+func (r *Recommendation) patchable() bool {
+	_, ok := patchTargets[r.Kind]
+	return ok
+}
+
+type recommendKey struct {
+	workloadKey
+	Container string
+}
+
+// 按工作负载及容器汇总用量并给出建议值，缺少metrics的容器无法推荐，跳过
+// 滚动更新期间副本的spec可能不同，当前值取最新创建的pod，与之后新建的副本一致
+func BuildRecommendations(podInfoList []*PodInfo, index ownerIndex, policy recommendPolicy) []*Recommendation {
+	recommendations := make(map[recommendKey]*Recommendation)
+	currentPods := make(map[recommendKey]*PodResource)
+	for _, podInfo := range podInfoList {
+		owner := index.resolve(podInfo.PodResource)
+		for name, containerResource := range podInfo.PodResource.Containers {
+			containerMetric, ok := podInfo.PodMetrics.Containers[name]
+			if !ok {
+				continue
+			}
+
+			key := recommendKey{owner, name}
+			r, ok := recommendations[key]
+			if !ok {
+				r = &Recommendation{workloadKey: owner, Container: name}
+				recommendations[key] = r
+			}
+			if current, ok := currentPods[key]; !ok || newerPod(&podInfo.PodResource, current) {
+				currentPods[key] = &podInfo.PodResource
+				r.Current = *containerResource
+			}
+			r.Replicas++
+			if containerMetric.CPUUsage > r.CPUUsage {
+				r.CPUUsage = containerMetric.CPUUsage
+			}
+			if containerMetric.MemUsage > r.MemUsage {
+				r.MemUsage = containerMetric.MemUsage
+			}
+		}
+	}
+
+	recommendationList := make([]*Recommendation, 0, len(recommendations))
+	for _, r := range recommendations {
+		r.Recommended = ContainerResource{
+			Name:        r.Container,
+			CPURequests: policy.size(r.CPUUsage, policy.headroom, policy.cpuStep),
+			CPULimits:   policy.size(r.CPUUsage, policy.limitHeadroom, policy.cpuStep),
+			MemRequest:  policy.size(r.MemUsage, policy.headroom, policy.memStep),
+			MemLimits:   policy.size(r.MemUsage, policy.limitHeadroom, policy.memStep),
+		}
+		r.CPUStatus = policy.status(r.CPUUsage, r.Current.CPURequests)
+		r.MemStatus = policy.status(r.MemUsage, r.Current.MemRequest)
+		recommendationList = append(recommendationList, r)
+	}
+
+	sort.Slice(recommendationList, func(i, j int) bool {
+		a, b := recommendationList[i], recommendationList[j]
+		if a.workloadKey != b.workloadKey {
+			return fmt.Sprint(a.workloadKey) < fmt.Sprint(b.workloadKey)
+		}
+		return a.Container < b.Container
+	})
+	return recommendationList
+}
+
+// 创建时间相同时按名称比较，保证结果与pod的遍历顺序无关
+func newerPod(a, b *PodResource) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.PodName > b.PodName
+}
+
+// 用量加上余量后按粒度向上取整，至少为一个粒度
+func (p recommendPolicy) size(usage int64, headroom float64, step int64) int64 {
+	value := int64(math.Ceil(float64(usage) * (1 + headroom/100)))
+	if step <= 0 {
+		return value
+	}
+	value = (value + step - 1) / step * step
+	if value < step {
+		return step
+	}
+	return value
+}
+
+func (p recommendPolicy) status(usage, request int64) provisionStatus {
+	ratio := calculateRatio(usage, request)
+	switch {
+	case request == 0 || ratio > p.underThreshold:
+		return provisionUnder
+	case ratio < p.overThreshold:
+		return provisionOver
+	default:
+		return provisionOK
+	}
+}
+
+func LoadRecommendations(ctx context.Context, namespace string, nslist []string, policy recommendPolicy) ([]*Recommendation, error) {
+	podInfoList, err := loadCombinedPodInfo(ctx, namespace, nslist)
+	if err != nil {
+		return nil, err
+	}
+	index, err := LoadOwnerIndex(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return BuildRecommendations(podInfoList, index, policy), nil
+}
+
+func PrintRecommendations(w io.Writer, recommendations []*Recommendation, showNamespace bool) {
+	var freedCPU, freedMem int64
+	rows := make([]tableRow, 0, len(recommendations))
+	for _, r := range recommendations {
+		cpu, mem := r.freed()
+		freedCPU += cpu
+		freedMem += mem
+
+		result := []string{
+			r.Kind,
+			r.Name,
+			r.Container,
+			fmt.Sprint(r.Replicas),
+			formatResourceUsage(r.Current.CPURequests, r.Current.CPULimits, r.CPUUsage, "CPU"),
+			convertToUnits(r.Recommended.CPURequests, "CPU") + "|" + convertToUnits(r.Recommended.CPULimits, "CPU"),
+			formatResourceUsage(r.Current.MemRequest, r.Current.MemLimits, r.MemUsage, "Memory"),
+			convertToUnits(r.Recommended.MemRequest, "Memory") + "|" + convertToUnits(r.Recommended.MemLimits, "Memory"),
+			provisionSummary(r.CPUStatus, r.MemStatus),
+		}
+		if showNamespace {
+			result = append([]string{r.Namespace}, result...)
+		}
+		rows = append(rows, tableRow{Cells: result})
+	}
+
+	header := recommendHeader
+	if showNamespace {
+		header = append([]string{namespaceHeader}, header...)
+	}
+	renderRows(w, header, rows)
+	fmt.Fprintf(w, "\n按补丁调整request后: cpu %s，内存 %s\n", freedSummary(freedCPU, "CPU"), freedSummary(freedMem, "Memory"))
+}
+
+func provisionSummary(cpu, mem provisionStatus) string {
+	text := map[provisionStatus]string{provisionOver: "过度分配", provisionUnder: "分配不足"}
+	var summary string
+	if cpu != provisionOK {
+		summary = "cpu" + text[cpu]
+	}
+	if mem != provisionOK {
+		if summary != "" {
+			summary += "，"
+		}
+		summary += "内存" + text[mem]
+	}
+	if summary == "" {
+		return "合理"
+	}
+	return summary
+}
+
+func freedSummary(value int64, resourceType string) string {
+	if value < 0 {
+		return "需增加 " + convertToUnits(-value, resourceType)
+	}
+	return "可释放 " + convertToUnits(value, resourceType)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildRecommendations(t *testing.T) {
+	policy, err := recommendOptions{Headroom: 20, LimitHeadroom: 100, CPUStep: "10m", MemStep: "16Mi", OverThreshold: 50, UnderThreshold: 100}.policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	web1 := newTestPodInfo("default", "web-7d9f-a", "node-a", "app", 1000, 100)
+	web2 := newTestPodInfo("default", "web-7d9f-b", "node-a", "app", 1000, 150)
+	db := newTestPodInfo("default", "db-0", "node-a", "db", 100, 180)
+	for _, podInfo := range []*PodInfo{web1, web2} {
+		podInfo.OwnerKind, podInfo.OwnerName = "ReplicaSet", "web-7d9f"
+		podInfo.PodResource.Containers["app"].MemRequest = 1 << 30
+		podInfo.PodMetrics.Containers["app"].MemUsage = 700 << 20
+	}
+	db.OwnerKind, db.OwnerName = "StatefulSet", "db"
+	index := ownerIndex{{"default", "ReplicaSet", "web-7d9f"}: {"default", "Deployment", "web"}}
+
+	recommendations := BuildRecommendations([]*PodInfo{web1, web2, db}, index, policy)
+	if len(recommendations) != 2 {
+		t.Fatalf("got %d recommendations, want 2", len(recommendations))
+	}
+
+	tests := []struct {
+		r          *Recommendation
+		key        workloadKey
+		replicas   int
+		cpuRequest int64
+		cpuLimit   int64
+		memRequest int64
+		cpuStatus  provisionStatus
+		memStatus  provisionStatus
+	}{
+		// 副本中最大用量150m，加20%余量后为180m；内存700Mi加余量后按16Mi取整为848Mi
+		{r: recommendations[0], key: workloadKey{"default", "Deployment", "web"}, replicas: 2, cpuRequest: 180, cpuLimit: 300, memRequest: 848 << 20, cpuStatus: provisionOver, memStatus: provisionOK},
+		// 用量超过request，未设置内存request
+		{r: recommendations[1], key: workloadKey{"default", "StatefulSet", "db"}, replicas: 1, cpuRequest: 220, cpuLimit: 360, memRequest: 16 << 20, cpuStatus: provisionUnder, memStatus: provisionUnder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key.Name, func(t *testing.T) {
+			r := tt.r
+			if r.workloadKey != tt.key || r.Replicas != tt.replicas {
+				t.Fatalf("got %v x%d, want %v x%d", r.workloadKey, r.Replicas, tt.key, tt.replicas)
+			}
+			if r.Recommended.CPURequests != tt.cpuRequest || r.Recommended.CPULimits != tt.cpuLimit || r.Recommended.MemRequest != tt.memRequest {
+				t.Errorf("recommended = %+v", r.Recommended)
+			}
+			if r.CPUStatus != tt.cpuStatus || r.MemStatus != tt.memStatus {
+				t.Errorf("status = %s/%s, want %s/%s", r.CPUStatus, r.MemStatus, tt.cpuStatus, tt.memStatus)
+			}
+		})
+	}
+
+	// 内存结论为ok，补丁不修改，不计入释放量
+	cpu, mem := recommendations[0].freed()
+	if cpu != 2*(1000-180) || mem != 0 {
+		t.Errorf("freed = %d/%d", cpu, mem)
+	}
+}
+
+func TestBuildRecommendationsCurrentFromNewestPod(t *testing.T) {
+	policy, err := recommendOptions{Headroom: 20, LimitHeadroom: 100, CPUStep: "10m", MemStep: "16Mi", OverThreshold: 50, UnderThreshold: 100}.policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 滚动更新中，新副本的request为500m
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	old1 := newTestPodInfo("default", "web-a", "node-a", "app", 1000, 100)
+	old2 := newTestPodInfo("default", "web-b", "node-a", "app", 1000, 100)
+	updated := newTestPodInfo("default", "web-c", "node-a", "app", 500, 100)
+	old1.CreatedAt, old2.CreatedAt, updated.CreatedAt = created, created, created.Add(time.Hour)
+	pods := []*PodInfo{old1, updated, old2}
+	for _, podInfo := range pods {
+		podInfo.OwnerKind, podInfo.OwnerName = "StatefulSet", "web"
+	}
+
+	for _, order := range [][]*PodInfo{pods, {pods[2], pods[1], pods[0]}, {pods[1], pods[0], pods[2]}} {
+		recommendations := BuildRecommendations(order, ownerIndex{}, policy)
+		if len(recommendations) != 1 {
+			t.Fatalf("got %d recommendations, want 1", len(recommendations))
+		}
+		if got := recommendations[0].Current.CPURequests; got != 500 {
+			t.Errorf("current cpu request = %d, want 500 from the newest pod", got)
+		}
+	}
+
+	// 创建时间相同时取名称较大的pod
+	updated.CreatedAt = created
+	updated.PodResource.PodName = "web-0"
+	for _, order := range [][]*PodInfo{pods, {pods[2], pods[1], pods[0]}} {
+		if got := BuildRecommendations(order, ownerIndex{}, policy)[0].Current.CPURequests; got != 1000 {
+			t.Errorf("current cpu request = %d, want 1000 from web-b", got)
+		}
+	}
+}
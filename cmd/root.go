@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"metrics.k8s.io/kube"
@@ -11,8 +14,10 @@ import (
 
 var (
 	kubetopLong = `
-	1. 展示pod资源申请与实际值的差异(资源申请与限额仅计算Containers，initContainers不作计算)
-	2. 展示node节点的资源剩余百分比/实际使用率并排序
+	1. 展示pod资源申请与实际值的差异(pod的资源申请与限额按调度器规则计算: 业务容器与sidecar之和、init容器峰值取较大值，再加上overhead)
+	2. 展示node节点的资源剩余百分比/实际使用率并排序(已结束的pod不计入，尚未调度的pod单独汇总为待调度需求)
+
+	退出码: 0 成功，1 其他错误，2 未通过--fail-if阈值检查，3 权限不足(RBAC)，4 命名空间不存在，5 metrics API不可用
 	`
 	kubetopExample = `
 	# 1. 展示 kube-system 命名空间下资源量并按照pod实际cpu使用量/request的百分比进行排序
@@ -24,26 +29,96 @@ var (
 	# 3. 展示 kube-system 命名空间下资源量并按照pod实际内存使用量/limit的百分比进行排序，同时显示各个容器的指标
 	kubetop pod -n kube-system -c --sort-by=mem.limit
 
-	# 3. 展示node节点cpu-request资源剩余率(默认)
+	# 4. 单独展示init容器及sidecar的request，pod级别的request已按调度器规则包含init容器、sidecar及overhead
+	kubetop pod -n kube-system -c --show-init
+
+	# 5. 在10分钟内每15秒采样一次，按p95用量/request排序并展示p50/p95/p99/max
+	kubetop pod -n kube-system --sample-duration 10m --sample-interval 15s
+
+	# 6. 展示所有命名空间下的pod并按照内存实际使用量/request的百分比进行排序
+	kubetop pod -A --sort-by=mem.request
+
+	# 7. 仅展示带有 app=payments 标签的pod，以及指定节点池的节点
+	kubetop pod -n payments -l app=payments
+	kubetop node -l pool=payments --field-selector spec.unschedulable=false
+
+	# 8. 按工作负载(Deployment/StatefulSet/DaemonSet/Job/CronJob)汇总资源量及副本数
+	kubetop workload -n kube-system --sort-by=mem.request
+	kubetop pod -n kube-system --group-by=owner
+
+	# 9. 展示node节点cpu-request资源剩余率(默认)
 	kubetop node --sort-by=cpu.request
 
-	# 4. 展示node节点资源剩余情况并按照内存实际使用率排序
+	# 10. 展示node节点资源剩余情况并按照内存实际使用率排序
 	kubetop node --sort-by=mem.util
 
-	# 5. pod排序规则包括cpu.request、mem.request、cpu.limit、mem.limit
-	     node排序规则包括cpu.request、mem.request、cpu.util、mem.util
+	# 11. 同时展示节点cpu(核)/内存(GiB)的可分配、容量、request、limit、使用、剩余及pod数/上限
+	kubetop node -o wide
+
+	# 12. 按可用区、机型或自定义的节点池标签分组，每组输出可分配/request/使用的小计
+	kubetop node --group-by=label:topology.kubernetes.io/zone
+	kubetop node --group-by=label:pool -o wide
+
+	# 13. 不统计被cordon、NotReady或带有NoSchedule/NoExecute污点的节点，状态及污点列可看到被排除的原因
+	kubetop node --exclude-unschedulable --sort-by=mem.request
+
+	# 14. 以csv/json等格式输出原始数值(CPU为毫核，内存为字节，比例为百分数)，便于导入表格或脚本处理
+	kubetop pod -n kube-system -c -o csv
+	kubetop node -o json
+
+	# 15. 每10秒刷新一次节点视图，request剩余率/使用率变化超过5个百分点的行高亮显示
+	kubetop node -w --interval 10s --watch-threshold 5
+
+	# 16. 交互界面: 选中节点回车查看其上的pod，再回车查看各容器；s切换排序，/输入过滤
+	kubetop ui
+
+	# 17. 按实际用量给出容器request/limit建议值(request预留30%余量)，并汇总可释放的资源
+	kubetop recommend -n payments --headroom 30
+	kubetop recommend -n payments --patch kustomize --patch-dir ./overlays/prod/rightsizing
+
+	# 18. 阈值检查: 任一节点cpu request剩余率低于15%或任一pod内存用量超过limit的90%时以退出码2结束，并列出未通过的行
+	kubetop node --fail-if 'node.cpu.request-remaining<15' --fail-if 'node.mem.request-remaining<15'
+	kubetop pod -A --fail-if 'pod.mem.limit>90' -o json > pods.json
+
+	# 19. 调整着色阈值: 节点实际cpu使用率70%显示黄色、85%显示红色；pod内存用量/request不低于120%标红
+	kubetop node --color-rule node.cpu.util=70,85
+	kubetop pod -n payments --color-rule pod.mem.request=,120
+	     也可写入~/.kubetop.yaml的colors字段，输出到管道或设置NO_COLOR时不着色
+
+	# 20. 使用~/.kubetop.yaml中名为payments的profile(命名空间、排序、阈值、context等)，命令行选项优先
+	kubetop --profile payments pod
+	kubetop --profile payments pod --sort-by cpu.limit
+
+	# 21. pod排序规则包括cpu.request、mem.request、cpu.limit、mem.limit
+	     node排序规则包括cpu.request、mem.request、cpu.util、mem.util、cpu.overcommit、mem.overcommit(limit总和/可分配，未设置limit的容器不计入)
 	
-	# 6. 命令行补齐:
+	# 22. 指定kubeconfig及context，或在pod内以集群内配置运行(未找到kubeconfig时自动使用)
+	kubetop --kubeconfig ~/.kube/prod.yaml --context prod-admin node
+	KUBECONFIG=~/.kube/a.yaml:~/.kube/b.yaml kubetop --context b pod -n default
+
+	# 23. 保存集群状态快照，之后离线回放
+	kubetop snapshot save -f capacity-ticket-1234.json
+	kubetop node --from-snapshot capacity-ticket-1234.json
+	kubetop pod -n kube-system --from-snapshot capacity-ticket-1234.json
+	kubetop diff last-week.json capacity-ticket-1234.json --threshold 5
+
+	# 24. 以Prometheus exporter方式运行，每30秒采集一次，指标位于 /metrics
+	kubetop serve --listen :9090 --interval 30s
+	curl 'localhost:9090/api/v1/namespaces/kube-system/pods?sortBy=mem.request&labelSelector=k8s-app=kube-dns'
+
+	# 25. 命令行补齐:
 	source <(kubetop completion zsh)
 	加入到$HOME/.bashrc或者/etc/profile永久生效
 	`
 	
 	namespace          string
+	allNamespaces      bool
 	podSortBy          string
 	nodeSortBy         string
 	podSortByContainer bool
-
-	NamespacesList []string
+	podSelector        Selector
+	nodeSelector       Selector
+	clientOptions      kube.ClientOptions
 )
 
 const (
@@ -59,12 +134,45 @@ var rootCmd = &cobra.Command{
 	Example:               kubetopExample,
 	DisableAutoGenTag:     true,
 	DisableFlagsInUseLine: true,
-	Use:                   "kubetop pod -n [namespace]|node --sort-by=cpu.request",
+	SilenceUsage:          true, // 错误由main统一输出并转换为退出码
+	SilenceErrors:         true,
+	Use:                   "kubetop pod -n [namespace]|-A|node --sort-by=cpu.request",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// 配置文件中的设置需在读取任何选项之前应用
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+
+		loglevel, _ := cmd.Flags().GetString("loglevel")
+		switch loglevel {
+		case "info":
+			kube.SetLogLevel(kube.INFO)
+		case "warning":
+			kube.SetLogLevel(kube.WARNING)
+		case "error":
+			kube.SetLogLevel(kube.ERROR)
+		default:
+			kube.SetLogLevel(kube.INFO)
+		}
+
+		kube.SetClientOptions(clientOptions)
+		if fromSnapshot != "" {
+			return useSnapshot(cmd)
+		}
+		return nil
+	},
 }
 
 func init() {
 	rootCmd.AddCommand(podCmd)
 	rootCmd.AddCommand(nodeCmd)
+	rootCmd.AddCommand(workloadCmd)
+	rootCmd.AddCommand(uiCmd)
+	rootCmd.AddCommand(recommendCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(serveCmd)
+	snapshotCmd.AddCommand(snapshotSaveCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	// 隐藏help子命令
@@ -73,38 +181,119 @@ func init() {
 	})
 
 	rootCmd.PersistentFlags().StringP("loglevel", "v", "warning", "设置日志级别")
+	rootCmd.PersistentFlags().StringVar(&clientOptions.Kubeconfig, "kubeconfig", "", "kubeconfig文件路径，默认合并KUBECONFIG环境变量及~/.kube/config，均不存在时使用集群内配置")
+	rootCmd.PersistentFlags().StringVar(&clientOptions.Context, "context", "", "使用kubeconfig中指定的context")
+	rootCmd.PersistentFlags().StringVar(&clientOptions.Cluster, "cluster", "", "使用kubeconfig中指定的cluster")
+	rootCmd.PersistentFlags().StringVar(&clientOptions.User, "user", "", "使用kubeconfig中指定的user")
+	rootCmd.PersistentFlags().StringVar(&clientOptions.As, "as", "", "以指定用户身份模拟请求")
+	rootCmd.PersistentFlags().StringArrayVar(&clientOptions.AsGroups, "as-group", nil, "以指定用户组身份模拟请求，可重复指定")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "配置文件路径，默认为~/.kubetop.yaml(不存在时忽略)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "使用配置文件profiles中的设置，默认为defaultProfile")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", colorAuto, "是否着色: auto(标准输出为终端且未设置NO_COLOR时着色) | always | never")
+	rootCmd.PersistentFlags().StringArrayVar(&colorRuleFlags, "color-rule", nil, "比例列的着色阈值 <列>=<warning>,<critical>，可重复指定，如 --color-rule node.cpu.util=70,85")
 
 	// 为 podCmd 添加 -n 或 --namespace 选项
 	podCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "指定查询的命名空间")
-	podCmd.MarkFlagRequired("namespace")
+	podCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "查询所有命名空间下的pod")
+	podCmd.MarkFlagsMutuallyExclusive("namespace", "all-namespaces")
 	podCmd.Flags().StringVar(&podSortBy, "sort-by", "cpu.request", "按cpu.request | mem.request | cpu.limit | mem.limit进行排序")
 	podCmd.Flags().BoolVarP(&podSortByContainer, "container", "c", false, "Sort by container-level resources")
+	podCmd.Flags().StringVarP(&podSelector.Label, "selector", "l", "", "按标签过滤pod，如 -l app=payments")
+	podCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "输出格式: json|yaml|csv|tsv，数值为原始值(毫核/字节/百分比)")
+	podCmd.Flags().StringVar(&podSelector.Field, "field-selector", "", "按字段过滤pod，如 --field-selector spec.nodeName=node1")
+	podCmd.Flags().DurationVar(&sampleDuration, "sample-duration", 0, "在该时长内多次采集用量，输出p50/p95/p99/max，如 --sample-duration 10m")
+	podCmd.Flags().DurationVar(&sampleInterval, "sample-interval", 15*time.Second, "采样间隔，metrics-server默认每15秒更新一次")
+	podCmd.Flags().BoolVar(&podShowInit, "show-init", false, "单独展示init容器: pod视图增加init阶段峰值request列，-c时增加init容器及sidecar行")
+	podCmd.Flags().StringVar(&podGroupBy, "group-by", "", "按owner分组，沿ownerReferences汇总到Deployment/StatefulSet/DaemonSet/Job/CronJob，等同于workload命令")
+
+	// workloadCmd与podCmd共用命名空间、选择器、排序及输出选项
+	workloadCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "指定查询的命名空间")
+	workloadCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "查询所有命名空间下的工作负载")
+	workloadCmd.MarkFlagsMutuallyExclusive("namespace", "all-namespaces")
+	workloadCmd.Flags().StringVar(&podSortBy, "sort-by", "cpu.request", "按cpu.request | mem.request | cpu.limit | mem.limit进行排序")
+	workloadCmd.Flags().StringVarP(&podSelector.Label, "selector", "l", "", "按标签过滤pod，如 -l app=payments")
+	workloadCmd.Flags().StringVar(&podSelector.Field, "field-selector", "", "按字段过滤pod")
+	workloadCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "输出格式: json|yaml|csv|tsv，数值为原始值(毫核/字节/百分比)")
+
+	// recommendCmd按工作负载内的容器给出建议值
+	recommendCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "指定查询的命名空间")
+	recommendCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "查询所有命名空间")
+	recommendCmd.MarkFlagsMutuallyExclusive("namespace", "all-namespaces")
+	recommendCmd.Flags().StringVarP(&podSelector.Label, "selector", "l", "", "按标签过滤pod")
+	recommendCmd.Flags().StringVar(&podSelector.Field, "field-selector", "", "按字段过滤pod")
+	recommendCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "输出格式: json|yaml|csv|tsv，数值为原始值(毫核/字节/百分比)")
+	recommendCmd.Flags().StringVar(&patchFormat, "patch", "", "按工作负载生成需调整容器的补丁: strategic|kustomize")
+	recommendCmd.Flags().StringVar(&patchDir, "patch-dir", "", "补丁写入的目录，默认输出到标准输出")
+	recommendCmd.Flags().BoolVar(&recommendOpts.AllowLimitDecrease, "allow-limit-decrease", false, "补丁中允许调低或新增limit，默认只调高已有的limit")
+	recommendCmd.MarkFlagsMutuallyExclusive("output", "patch")
+	recommendCmd.Flags().Float64Var(&recommendOpts.Headroom, "headroom", 20, "建议request在实际用量基础上预留的余量(百分比)")
+	recommendCmd.Flags().Float64Var(&recommendOpts.LimitHeadroom, "limit-headroom", 100, "建议limit在实际用量基础上预留的余量(百分比)")
+	recommendCmd.Flags().StringVar(&recommendOpts.CPUStep, "cpu-step", "10m", "建议cpu向上取整的粒度")
+	recommendCmd.Flags().StringVar(&recommendOpts.MemStep, "mem-step", "16Mi", "建议内存向上取整的粒度")
+	recommendCmd.Flags().Float64Var(&recommendOpts.OverThreshold, "over-threshold", 50, "用量/request低于该百分比视为过度分配")
+	recommendCmd.Flags().Float64Var(&recommendOpts.UnderThreshold, "under-threshold", 100, "用量/request高于该百分比视为分配不足")
+
+	for _, cmd := range []*cobra.Command{podCmd, nodeCmd, workloadCmd} {
+		cmd.Flags().BoolVarP(&watchMode, "watch", "w", false, "像top一样周期刷新")
+		cmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Second, "watch模式的刷新间隔")
+		cmd.Flags().Float64Var(&watchThreshold, "watch-threshold", 10, "watch模式下比例变化超过该值(百分点)的行高亮显示")
+	}
+
+	// 交互界面的刷新间隔及节点过滤
+	uiCmd.Flags().DurationVar(&watchInterval, "interval", 5*time.Second, "自动刷新间隔")
+	uiCmd.Flags().StringVarP(&nodeSelector.Label, "selector", "l", "", "按标签过滤节点")
+	uiCmd.Flags().StringVar(&nodeSelector.Field, "field-selector", "", "按字段过滤节点")
+
+	snapshotSaveCmd.Flags().StringVarP(&snapshotFile, "file", "f", "", "快照文件路径，默认为当前目录下的kubetop-snapshot-<时间>.json")
+	for _, cmd := range []*cobra.Command{podCmd, nodeCmd, workloadCmd, recommendCmd} {
+		cmd.Flags().StringVar(&fromSnapshot, "from-snapshot", "", "读取kubetop snapshot save保存的快照文件，离线输出")
+	}
+
+	// diffCmd对比两个快照，不指定命名空间时对比所有命名空间的pod
+	diffCmd.Flags().StringVarP(&namespace, "namespace", "n", "", "只对比指定命名空间下的pod")
+	diffCmd.Flags().StringVarP(&podSelector.Label, "selector", "l", "", "按标签过滤pod")
+	diffCmd.Flags().StringVar(&nodeSelector.Label, "node-selector", "", "按标签过滤节点")
+	diffCmd.Flags().Float64Var(&diffThreshold, "threshold", 10, "节点cpu或内存request剩余率下降超过该值(百分点)时标记")
+
+	// serveCmd以Prometheus格式暴露所有命名空间下的数据
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":9090", "HTTP监听地址")
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", 30*time.Second, "采集间隔")
+	serveCmd.Flags().StringVarP(&podSelector.Label, "selector", "l", "", "按标签过滤pod")
+	serveCmd.Flags().StringVar(&nodeSelector.Label, "node-selector", "", "按标签过滤节点")
 
 	// 为nodeCmd添加--sort选项
-	nodeCmd.Flags().StringVar(&nodeSortBy, "sort-by", "cpu.request", "按cpu.request | cpu.util | mem.request | mem.util排序")
+	nodeCmd.Flags().StringVar(&nodeSortBy, "sort-by", "cpu.request", "按cpu.request | cpu.util | mem.request | mem.util | cpu.overcommit | mem.overcommit排序")
+	nodeCmd.Flags().StringVarP(&nodeSelector.Label, "selector", "l", "", "按标签过滤节点，如 -l node.kubernetes.io/instance-type=c6.xlarge")
+	nodeCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "输出格式: json|yaml|csv|tsv，数值为原始值(毫核/字节/百分比)；wide为表格，另外展示可分配/容量/request/limit/使用/剩余(核、GiB)及pod数")
+	nodeCmd.Flags().StringVar(&nodeSelector.Field, "field-selector", "", "按字段过滤节点，如 --field-selector spec.unschedulable=false")
+	nodeCmd.Flags().BoolVar(&excludeUnschedulable, "exclude-unschedulable", false, "排除不可调度的节点(NotReady、已cordon或带有NoSchedule/NoExecute污点)，不参与排序、分组小计及--fail-if检查")
+	nodeCmd.Flags().StringVar(&nodeGroupBy, "group-by", "", "按节点标签分组并输出每组的小计，格式为label:<key>，如 label:node.kubernetes.io/instance-type；未设置该标签的节点归入<未设置>，-o json等只输出各组小计")
+
+	// 阈值检查，用于CI及发布流程中阻断
+	podCmd.Flags().StringArrayVar(&failIf, "fail-if", nil, "任一pod满足该条件时以退出码2结束，可重复指定，如 --fail-if 'pod.mem.limit>90'，指标: cpu.request | mem.request | cpu.limit | mem.limit")
+	nodeCmd.Flags().StringArrayVar(&failIf, "fail-if", nil, "任一节点满足该条件时以退出码2结束，可重复指定，如 --fail-if 'node.cpu.request-remaining<15'，指标: cpu.request-remaining | mem.request-remaining | cpu.util | mem.util | cpu.overcommit | mem.overcommit")
 }
 
 func Execute() error {
-	timeout := time.Second * 5 // 全局超时时间
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	// 单次采集的超时由各命令设置(requestTimeout)，watch模式下持续运行直到收到中断信号
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	loglevel, _ := rootCmd.Flags().GetString("loglevel")
-	switch loglevel {
-	case "info":
-		kube.SetLogLevel(kube.INFO)
-	case "warning":
-		kube.SetLogLevel(kube.WARNING)
-	case "error":
-		kube.SetLogLevel(kube.ERROR)
-	default:
-		kube.SetLogLevel(kube.INFO)
+	// 补全时不会执行PersistentPreRun，此处应用配置文件并按已解析的flag构建客户端
+	completeNamespace := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if err := applyConfig(cmd); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		kube.SetClientOptions(clientOptions)
+		nslist, err := ListNamespace(cmd.Context())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return nslist, cobra.ShellCompDirectiveDefault
 	}
-
-	NamespacesList = ListNamespace(ctx)
-	podCmd.RegisterFlagCompletionFunc("namespace", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return NamespacesList, cobra.ShellCompDirectiveDefault
-	})
+	podCmd.RegisterFlagCompletionFunc("namespace", completeNamespace)
+	workloadCmd.RegisterFlagCompletionFunc("namespace", completeNamespace)
+	recommendCmd.RegisterFlagCompletionFunc("namespace", completeNamespace)
 
 	return rootCmd.ExecuteContext(ctx)
 }
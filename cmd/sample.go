@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"metrics.k8s.io/kube"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	sampleDuration time.Duration
+	sampleInterval time.Duration
+
+	podSampleHeader       = []string{"节点名称", "pod名称", "cpu request|limit", "cpu p50|p95|p99|max", "cpu p95/request占比", "cpu max/limit占比", "内存 request|limit", "内存 p50|p95|p99|max", "内存 p95/request占比", "内存 max/limit占比", "样本数"}
+	containerSampleHeader = []string{"运行节点", "pod名称", "容器名称", "cpu request|limit", "cpu p50|p95|p99|max", "cpu p95/request占比", "cpu max/limit占比", "内存 request|limit", "内存 p50|p95|p99|max", "内存 p95/request占比", "内存 max/limit占比", "样本数"}
+)
+
+// 单个容器(或整个pod)在采样期间的用量序列，CPU单位为毫核，内存单位为字节
+type usageSeries struct {
+	CPU []int64
+	Mem []int64
+}
+
+func (s *usageSeries) add(cpu, mem int64) {
+	s.CPU = append(s.CPU, cpu)
+	s.Mem = append(s.Mem, mem)
+}
+
+// pod的采样序列，Total为每次采样时各容器用量之和
+type podSeries struct {
+	PodName    string
+	Containers map[string]*usageSeries
+	Total      usageSeries
+}
+
+// 用量分布
+type usageStats struct {
+	P50, P95, P99, Max int64
+}
+
+func validateSampling() error {
+	if sampleDuration == 0 {
+		return nil
+	}
+	switch {
+	case sampleDuration < 0 || sampleInterval <= 0:
+		return fmt.Errorf("--sample-duration 及 --sample-interval 必须大于0: %s/%s", sampleDuration, sampleInterval)
+	case watchMode:
+		return errors.New("--sample-duration 不能与 --watch 同时使用")
+	case outputFormat != "":
+		return errors.New("--sample-duration 暂不支持 -o")
+	case podGroupBy != "":
+		return errors.New("--sample-duration 暂不支持 --group-by")
+	}
+	return nil
+}
+
+// 在duration内每隔interval采集一次pod metrics，首次采集立即进行
+// 首次采集失败直接返回错误；采样途中收到中断信号时提前结束，使用已采集的样本
+func SampleK8sMetrics(ctx context.Context, namespace string, selector Selector, duration, interval time.Duration) (map[string]*podSeries, error) {
+	series := make(map[string]*podSeries)
+	total := int(duration/interval) + 1
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 1; i <= total; i++ {
+		sampleCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		metrics, err := LoadK8sMetrics(sampleCtx, namespace, selector)
+		cancel()
+		switch {
+		case err == nil:
+		case i == 1:
+			return nil, err
+		case ctx.Err() != nil:
+			i = total
+			continue
+		default:
+			// 偶发失败只丢弃本次样本
+			fmt.Fprintf(os.Stderr, "\n第%d次采样失败，已跳过: %v\n", i, err)
+		}
+
+		for key, podMetric := range metrics {
+			s, ok := series[key]
+			if !ok {
+				s = &podSeries{PodName: podMetric.PodName, Containers: make(map[string]*usageSeries)}
+				series[key] = s
+			}
+			var cpu, mem int64
+			for name, containerMetric := range podMetric.Containers {
+				if s.Containers[name] == nil {
+					s.Containers[name] = &usageSeries{}
+				}
+				s.Containers[name].add(containerMetric.CPUUsage, containerMetric.MemUsage)
+				cpu += containerMetric.CPUUsage
+				mem += containerMetric.MemUsage
+			}
+			s.Total.add(cpu, mem)
+		}
+		fmt.Fprintf(os.Stderr, "\r采样 %d/%d", i, total)
+
+		if i == total {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			i = total
+		case <-ticker.C:
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	return series, nil
+}
+
+// 最近秩法计算百分位数
+func percentiles(values []int64) usageStats {
+	if len(values) == 0 {
+		return usageStats{}
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := func(p float64) int64 {
+		return sorted[int(math.Ceil(p/100*float64(len(sorted))))-1]
+	}
+	return usageStats{P50: rank(50), P95: rank(95), P99: rank(99), Max: sorted[len(sorted)-1]}
+}
+
+// 以各容器的p95用量作为pod的用量，排序及占比计算沿用单次采集的逻辑
+func (s *podSeries) p95Metrics() PodMetrics {
+	metrics := PodMetrics{PodName: s.PodName, Containers: make(map[string]*ContainerMetrics, len(s.Containers))}
+	for name, series := range s.Containers {
+		metrics.Containers[name] = &ContainerMetrics{
+			Name:     name,
+			CPUUsage: percentiles(series.CPU).P95,
+			MemUsage: percentiles(series.Mem).P95,
+		}
+	}
+	return metrics
+}
+
+// 先采集pod的资源配置，再在采样期间多次采集用量
+func LoadSampledPodInfo(ctx context.Context, namespace string, nslist []string) ([]*PodInfo, map[string]*podSeries, error) {
+	if namespace != metav1.NamespaceAll && !IsNamespaceExist(namespace, nslist) {
+		return nil, nil, fmt.Errorf("%w: %s", kube.ErrNamespaceNotFound, namespace)
+	}
+	loadCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	resources, err := LoadK8sResource(loadCtx, namespace, podSelector)
+	cancel()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	series, err := SampleK8sMetrics(ctx, namespace, podSelector, sampleDuration, sampleInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+	metrics := make(map[string]PodMetrics, len(series))
+	for key, s := range series {
+		metrics[key] = s.p95Metrics()
+	}
+	return SortPodInfo(CombinePodInfo(resources, metrics)), series, nil
+}
+
+func PrintSampledResult(ctx context.Context, namespace string, nslist []string) error {
+	podInfoList, series, err := LoadSampledPodInfo(ctx, namespace, nslist)
+	if err != nil {
+		return err
+	}
+	header, rows := sampledPodTableRows(podInfoList, series, namespace == metav1.NamespaceAll)
+	renderRows(os.Stdout, header, rows)
+	return nil
+}
+
+func sampledPodTableRows(podInfoList []*PodInfo, series map[string]*podSeries, showNamespace bool) ([]string, []tableRow) {
+	if !showNamespace {
+		podInfoList = limitDaemonSetPods(podInfoList)
+	}
+	rows := make([]tableRow, 0, len(podInfoList))
+	for _, podInfo := range podInfoList {
+		s := series[encode(podInfo.PodResource.Namespace, podInfo.PodResource.PodName)]
+		podKey := podInfo.PodResource.Namespace + "/" + podInfo.PodResource.PodName
+		prefix := []string{podInfo.NodeName, podInfo.PodResource.PodName}
+		if showNamespace {
+			prefix = append([]string{podInfo.PodResource.Namespace}, prefix...)
+		}
+
+		if !podSortByContainer {
+			t := podInfo.totals()
+			rows = append(rows, sampledRow(podKey, prefix, t.CPURequests, t.CPULimits, t.MemRequests, t.MemLimits, &s.Total))
+			continue
+		}
+		for name, containerResource := range podInfo.PodResource.Containers {
+			containerSeries, ok := s.Containers[name]
+			if !ok {
+				containerSeries = &usageSeries{}
+			}
+			rows = append(rows, sampledRow(podKey+"/"+name, append(prefix, name),
+				containerResource.CPURequests, containerResource.CPULimits, containerResource.MemRequest, containerResource.MemLimits, containerSeries))
+		}
+	}
+
+	header := podSampleHeader
+	if podSortByContainer {
+		header = containerSampleHeader
+	}
+	if showNamespace {
+		header = append([]string{namespaceHeader}, header...)
+	}
+	return header, rows
+}
+
+// p95与request比较，max与limit比较
+func sampledRow(key string, prefix []string, cpuRequest, cpuLimit, memRequest, memLimit int64, series *usageSeries) tableRow {
+	cpu, mem := percentiles(series.CPU), percentiles(series.Mem)
+	ratios := []float64{
+		calculateRatio(cpu.P95, cpuRequest),
+		calculateRatio(cpu.Max, cpuLimit),
+		calculateRatio(mem.P95, memRequest),
+		calculateRatio(mem.Max, memLimit),
+	}
+	cells := append(append([]string(nil), prefix...),
+		convertToUnits(cpuRequest, "CPU")+"|"+convertToUnits(cpuLimit, "CPU"),
+		formatStats(cpu, "CPU"),
+		ratioCell("pod.cpu.request", ratios[0]),
+		ratioCell("pod.cpu.limit", ratios[1]),
+		convertToUnits(memRequest, "Memory")+"|"+convertToUnits(memLimit, "Memory"),
+		formatStats(mem, "Memory"),
+		ratioCell("pod.mem.request", ratios[2]),
+		ratioCell("pod.mem.limit", ratios[3]),
+		fmt.Sprint(len(series.CPU)),
+	)
+	return tableRow{Key: key, Ratios: ratios, Cells: cells}
+}
+
+func formatStats(stats usageStats, resourceType string) string {
+	values := []string{
+		convertToUnits(stats.P50, resourceType),
+		convertToUnits(stats.P95, resourceType),
+		convertToUnits(stats.P99, resourceType),
+		convertToUnits(stats.Max, resourceType),
+	}
+	return strings.Join(values, "|")
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestPercentiles(t *testing.T) {
+	values := make([]int64, 0, 100)
+	for i := 100; i >= 1; i-- {
+		values = append(values, int64(i))
+	}
+
+	tests := []struct {
+		name   string
+		values []int64
+		want   usageStats
+	}{
+		{name: "empty", values: nil, want: usageStats{}},
+		{name: "single", values: []int64{42}, want: usageStats{42, 42, 42, 42}},
+		{name: "1..100", values: values, want: usageStats{50, 95, 99, 100}},
+		{name: "idle with spike", values: []int64{10, 10, 10, 10, 500}, want: usageStats{10, 500, 500, 500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentiles(tt.values); got != tt.want {
+				t.Errorf("percentiles = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleK8sMetrics(t *testing.T) {
+	ctx := fakeContext(t,
+		[]runtime.Object{newPod("default", "web-app-1", "node-a", newContainer("app", resourceList("100m", "100M"), nil))},
+		[]metricsv1beta1.PodMetrics{newPodMetrics("default", "web-app-1", map[string]corev1.ResourceList{"app": resourceList("50m", "50M")})},
+		nil)
+
+	series, err := SampleK8sMetrics(ctx, "default", Selector{}, 20*time.Millisecond, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := series[encode("default", "web-app-1")]
+	if !ok {
+		t.Fatal("web-app-1 not sampled")
+	}
+	if len(s.Total.CPU) != 3 || len(s.Containers["app"].Mem) != 3 {
+		t.Fatalf("samples = %d/%d, want 3", len(s.Total.CPU), len(s.Containers["app"].Mem))
+	}
+	if got := s.p95Metrics().Containers["app"].CPUUsage; got != 50 {
+		t.Errorf("p95 cpu = %d, want 50", got)
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"metrics.k8s.io/kube"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	serveListen   string
+	serveInterval time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve usage ratios as Prometheus metrics and a JSON API",
+	Long: `以Prometheus格式在 /metrics 暴露周期采集的节点及容器比例，
+同时提供与CLI一致的JSON API(每次请求实时采集):
+  GET /api/v1/nodes?sortBy=mem.util&labelSelector=pool=payments
+  GET /api/v1/pods?sortBy=cpu.limit
+  GET /api/v1/namespaces/{ns}/pods?sortBy=mem.request&labelSelector=app=web&container=true`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveInterval <= 0 {
+			return errors.New("--interval 必须大于0")
+		}
+
+		c := &collector{}
+		// 首次采集失败(权限、metrics API不可用等)直接退出，便于部署时发现问题
+		if err := c.collect(cmd.Context()); err != nil {
+			return err
+		}
+		go c.run(cmd.Context(), serveInterval)
+
+		return serveHTTP(cmd.Context(), serveListen, newServeMux(c))
+	},
+	Args: cobra.NoArgs,
+}
+
+// 周期采集节点及所有命名空间下pod的数据，供HTTP请求读取最近一次的结果
+type collector struct {
+	mu      sync.RWMutex
+	nodes   []nodeInfo
+	pods    []*PodInfo
+	updated time.Time
+	errors  int // 累计采集失败次数
+}
+
+func (c *collector) collect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	nodes, err := LoadNodeInfo(ctx, nodeSelector)
+	if err == nil {
+		var pods []*PodInfo
+		pods, err = loadCombinedPodInfo(ctx, metav1.NamespaceAll, nil)
+		// 没有匹配的pod(如工作负载缩容到0)是正常结果，清空pod指标而不是保留上一次的序列
+		var noPods noPodsError
+		if errors.As(err, &noPods) {
+			pods, err = []*PodInfo{}, nil
+		}
+		if err == nil {
+			c.mu.Lock()
+			c.nodes, c.pods, c.updated = nodes, pods, time.Now()
+			c.mu.Unlock()
+			return nil
+		}
+	}
+
+	c.mu.Lock()
+	c.errors++
+	c.mu.Unlock()
+	return err
+}
+
+// 采集失败时保留上一次的结果，通过kubetop_collect_errors_total及采集时间暴露
+func (c *collector) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.collect(ctx); err != nil {
+				kube.Warning(err, "采集失败")
+			}
+		}
+	}
+}
+
+func (c *collector) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeExposition(w, c.nodes, c.pods, c.updated, c.errors)
+}
+
+func newServeMux(c *collector) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", c.handleMetrics)
+	registerAPI(mux)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+// 监听直到ctx被取消(Ctrl-C/SIGTERM)，之后优雅关闭
+func serveHTTP(ctx context.Context, listen string, handler http.Handler) error {
+	server := &http.Server{Addr: listen, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+	kube.Info(nil, "监听 "+listen)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
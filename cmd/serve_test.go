@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"metrics.k8s.io/kube"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestServeMetrics(t *testing.T) {
+	web := newTestPodInfo("default", "web-1", "node-a", "app", 200, 50)
+	web.PodResource.Containers["app"].MemLimits = 1000
+	web.PodMetrics.Containers["app"].MemUsage = 250
+	dns := newTestPodInfo("kube-system", `odd"name`, "node-a", "dns", 0, 10)
+	dns.PodResource.Containers["dns"].CPULimits = 100
+	c := &collector{
+		nodes:   []nodeInfo{{NodeName: "node-a", CPUPercentage: 75, MemoryPercentage: 50, NodeCPUUtilization: 12.5, MetricsAvailable: true}},
+		pods:    []*PodInfo{web, dns},
+		updated: time.Unix(1700000000, 0),
+		errors:  2,
+	}
+
+	server := httptest.NewServer(newServeMux(c))
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"# TYPE kubetop_node_cpu_request_remaining_ratio gauge\n",
+		`kubetop_node_cpu_request_remaining_ratio{node="node-a"} 0.75` + "\n",
+		`kubetop_node_cpu_utilization_ratio{node="node-a"} 0.125` + "\n",
+		`kubetop_container_cpu_usage_to_request_ratio{namespace="default",pod="web-1",container="app",node="node-a"} 0.25` + "\n",
+		`kubetop_container_memory_usage_to_limit_ratio{namespace="default",pod="web-1",container="app",node="node-a"} 0.25` + "\n",
+		"kubetop_collect_timestamp_seconds 1.7e+09\n",
+		"kubetop_collect_errors_total 2\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("missing %q in\n%s", want, body)
+		}
+	}
+	// 未设置request的容器不输出对应比例，标签值需转义
+	if strings.Contains(string(body), `kubetop_container_cpu_usage_to_request_ratio{namespace="kube-system"`) {
+		t.Error("ratio with zero request should be omitted")
+	}
+	if !strings.Contains(string(body), `pod="odd\"name"`) {
+		t.Error("label value not escaped")
+	}
+}
+
+func TestCollectScaledToZero(t *testing.T) {
+	clients, err := kube.NewFakeClients(
+		[]runtime.Object{
+			newNode("node-a", "4", "8G"),
+			newPod("default", "web-1", "node-a", newContainer("app", resourceList("200m", "200M"), nil)),
+		},
+		[]metricsv1beta1.PodMetrics{newPodMetrics("default", "web-1", map[string]corev1.ResourceList{"app": resourceList("100m", "100M")})},
+		[]metricsv1beta1.NodeMetrics{newNodeMetrics("node-a", "1", "2G")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := kube.WithClients(context.Background(), clients)
+
+	c := &collector{}
+	if err := c.collect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.pods) != 1 {
+		t.Fatalf("got %d pods, want 1", len(c.pods))
+	}
+	updated := c.updated
+
+	// 工作负载缩容到0后，pod的指标序列应消失而不是计为采集失败
+	if err := clients.K8s.CoreV1().Pods("default").Delete(ctx, "web-1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.collect(ctx); err != nil {
+		t.Fatalf("collect with no pods: %v", err)
+	}
+	if len(c.pods) != 0 || c.errors != 0 || !c.updated.After(updated) {
+		t.Errorf("pods = %d, errors = %d, updated = %v (was %v)", len(c.pods), c.errors, c.updated, updated)
+	}
+
+	recorder := httptest.NewRecorder()
+	c.handleMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if body := recorder.Body.String(); strings.Contains(body, `pod="web-1"`) {
+		t.Errorf("stale pod series in\n%s", body)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"metrics.k8s.io/kube"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotFile string
+	fromSnapshot string
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Record cluster state for offline replay",
+	Args:  cobra.NoArgs,
+}
+
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save",
+	Short: "Save pods, nodes and metrics into a timestamped snapshot file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(cmd.Context(), requestTimeout)
+		defer cancel()
+		snapshot, err := kube.CaptureSnapshot(ctx)
+		if err != nil {
+			return err
+		}
+
+		path := snapshotFile
+		if path == "" {
+			path = fmt.Sprintf("kubetop-snapshot-%s.json", snapshot.Timestamp.Format("20060102-150405"))
+		}
+		if err := snapshot.Save(path); err != nil {
+			return err
+		}
+		fmt.Printf("快照已写入 %s: %d个节点，%d个pod\n", path, len(snapshot.Nodes), len(snapshot.Pods))
+		return nil
+	},
+	Args: cobra.NoArgs,
+}
+
+// 以快照文件替代实时集群作为数据来源，之后的采集均读取快照
+func useSnapshot(cmd *cobra.Command) error {
+	if watchMode || sampleDuration > 0 {
+		return errors.New("--from-snapshot 为静态数据，不能与 --watch 或 --sample-duration 同时使用")
+	}
+	snapshot, err := kube.LoadSnapshot(fromSnapshot)
+	if err != nil {
+		return err
+	}
+	clients, err := snapshot.Clients()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "使用快照 %s，采集于 %s\n", fromSnapshot, snapshot.Timestamp.Format(time.RFC3339))
+	cmd.SetContext(kube.WithClients(cmd.Context(), clients))
+	return nil
+}
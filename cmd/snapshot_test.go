@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"metrics.k8s.io/kube"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func TestSnapshotReplay(t *testing.T) {
+	pod := newPod("default", "web-app-1", "node-a", newContainer("app", resourceList("1", "2G"), nil))
+	pod.Labels = map[string]string{"app": "web"}
+	podMetrics := newPodMetrics("default", "web-app-1", map[string]corev1.ResourceList{"app": resourceList("500m", "1G")})
+	podMetrics.Labels = pod.Labels
+	// 已结束的pod及被cordon的节点需要由字段选择器过滤
+	finished := newPod("default", "job-1", "node-a", newContainer("job", resourceList("2", "4G"), nil))
+	finished.Status.Phase = corev1.PodSucceeded
+	cordoned := newNode("node-b", "4", "8G")
+	cordoned.Spec.Unschedulable = true
+	live := fakeContext(t,
+		[]runtime.Object{newNode("node-a", "4", "8G"), cordoned, pod, finished},
+		[]metricsv1beta1.PodMetrics{podMetrics},
+		[]metricsv1beta1.NodeMetrics{newNodeMetrics("node-a", "1", "2G"), newNodeMetrics("node-b", "1", "2G")})
+
+	snapshot, err := kube.CaptureSnapshot(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := kube.LoadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients, err := loaded.Clients()
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline := kube.WithClients(context.Background(), clients)
+
+	for name, ctx := range map[string]context.Context{"live": live, "offline": offline} {
+		nodeInfoList, err := LoadNodeInfo(ctx, Selector{Field: "spec.unschedulable=false"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(nodeInfoList) != 1 || !almostEqual(nodeInfoList[0].CPUPercentage, 75) || !almostEqual(nodeInfoList[0].NodeCPUUtilization, 25) {
+			t.Errorf("%s: nodes = %+v", name, nodeInfoList)
+		}
+
+		finishedPods, err := LoadK8sResource(ctx, "default", Selector{Field: "status.phase=Succeeded"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(finishedPods) != 1 {
+			t.Errorf("%s: --field-selector status.phase=Succeeded returned %d pods, want 1", name, len(finishedPods))
+		}
+		// 回放只支持白名单中的字段，命名空间下没有pod时同样报错而不是返回空结果
+		for _, selector := range []struct{ namespace, field string }{
+			{"default", "spec.hostname=web"},
+			{"default", "spec.schedulerName=default-scheduler"},
+			{"empty", "spec.schedulerName=default-scheduler"},
+		} {
+			_, err := LoadK8sResource(ctx, selector.namespace, Selector{Field: selector.field})
+			var noPods noPodsError
+			if err == nil || errors.As(err, &noPods) {
+				t.Errorf("%s: field selector %s in %s should fail, got %v", name, selector.field, selector.namespace, err)
+			}
+		}
+
+		resources, err := LoadK8sResource(ctx, "default", Selector{Label: "app=web"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		metrics, err := LoadK8sMetrics(ctx, "default", Selector{Label: "app=web"})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		podInfoList := CombinePodInfo(resources, metrics)
+		if len(podInfoList) != 1 || !almostEqual(podInfoList[0].CPUUsageToRequestRatio, 50) {
+			t.Errorf("%s: pods = %d", name, len(podInfoList))
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"metrics.k8s.io/kube"
+)
+
+/*
+--fail-if 阈值表达式，格式为 <对象>.<指标><比较符><百分比>，如:
+  - node.cpu.request-remaining<15   节点cpu request剩余率低于15%
+  - node.mem.util>=90               节点实际内存使用率不低于90%
+  - pod.mem.limit>90                pod内存用量/limit超过90%
+比较符支持 < <= > >=，百分比可带%。可重复指定，任一行满足任一表达式即视为未通过
+*/
+
+var failIf []string
+
+// 各对象支持的指标，取值与表格中的百分比一致
+var (
+	nodeThresholdMetrics = map[string]func(nodeInfo) (float64, bool){
+		"cpu.request-remaining": func(n nodeInfo) (float64, bool) { return n.CPUPercentage, n.CPUTotal > 0 },
+		"mem.request-remaining": func(n nodeInfo) (float64, bool) { return n.MemoryPercentage, n.MemoryTotal > 0 },
+		"cpu.util":              func(n nodeInfo) (float64, bool) { return n.NodeCPUUtilization, n.MetricsAvailable && n.CPUTotal > 0 },
+		"mem.util":              func(n nodeInfo) (float64, bool) { return n.NodeMemUtilization, n.MetricsAvailable && n.MemoryTotal > 0 },
+		"cpu.overcommit":        func(n nodeInfo) (float64, bool) { return n.CPUOvercommit, n.CPUTotal > 0 },
+		"mem.overcommit":        func(n nodeInfo) (float64, bool) { return n.MemoryOvercommit, n.MemoryTotal > 0 },
+	}
+	// 未设置request/limit的pod无法计算比例，不参与判断
+	podThresholdMetrics = map[string]func(*PodInfo, podTotals) (float64, bool){
+		"cpu.request": func(p *PodInfo, t podTotals) (float64, bool) { return p.CPUUsageToRequestRatio, t.CPURequests > 0 },
+		"mem.request": func(p *PodInfo, t podTotals) (float64, bool) { return p.MemUsageToRequestRatio, t.MemRequests > 0 },
+		"cpu.limit":   func(p *PodInfo, t podTotals) (float64, bool) { return p.CPUUsageToLimitsRatio, t.CPULimits > 0 },
+		"mem.limit":   func(p *PodInfo, t podTotals) (float64, bool) { return p.MemUsageToLimitsRatio, t.MemLimits > 0 },
+	}
+)
+
+// 按长度从长到短匹配，避免<=被识别为<
+var thresholdOperators = []string{"<=", ">=", "<", ">"}
+
+type threshold struct {
+	Expr   string
+	Metric string
+	Op     string
+	Value  float64
+}
+
+func (t threshold) exceeded(value float64) bool {
+	switch t.Op {
+	case "<":
+		return value < t.Value
+	case "<=":
+		return value <= t.Value
+	case ">":
+		return value > t.Value
+	default:
+		return value >= t.Value
+	}
+}
+
+// 解析属于scope(node或pod)的表达式
+func parseThresholds(exprs []string, scope string) ([]threshold, error) {
+	thresholds := make([]threshold, 0, len(exprs))
+	for _, expr := range exprs {
+		t, err := parseThreshold(expr, scope)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, nil
+}
+
+func parseThreshold(expr, scope string) (threshold, error) {
+	s := strings.ReplaceAll(expr, " ", "")
+	for _, op := range thresholdOperators {
+		i := strings.Index(s, op)
+		if i < 0 {
+			continue
+		}
+		metric, value := s[:i], strings.TrimSuffix(s[i+len(op):], "%")
+		if !strings.HasPrefix(metric, scope+".") {
+			return threshold{}, fmt.Errorf("--fail-if %s: 该命令只支持%s.开头的指标", expr, scope)
+		}
+		metric = strings.TrimPrefix(metric, scope+".")
+		if !validThresholdMetric(scope, metric) {
+			return threshold{}, fmt.Errorf("--fail-if %s: 未知的指标 %s，可选 %s", expr, metric, strings.Join(thresholdMetricNames(scope), " | "))
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return threshold{}, fmt.Errorf("--fail-if %s: 无效的百分比 %s", expr, value)
+		}
+		return threshold{Expr: expr, Metric: metric, Op: op, Value: v}, nil
+	}
+	return threshold{}, fmt.Errorf("--fail-if %s: 缺少比较符 < <= > >=", expr)
+}
+
+func validThresholdMetric(scope, metric string) bool {
+	if scope == "node" {
+		_, ok := nodeThresholdMetrics[metric]
+		return ok
+	}
+	_, ok := podThresholdMetrics[metric]
+	return ok
+}
+
+func thresholdMetricNames(scope string) []string {
+	var names []string
+	if scope == "node" {
+		for name := range nodeThresholdMetrics {
+			names = append(names, name)
+		}
+	} else {
+		for name := range podThresholdMetrics {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 校验--fail-if与其他选项的组合并解析表达式
+func parseFailIf(scope string) ([]threshold, error) {
+	if len(failIf) == 0 {
+		return nil, nil
+	}
+	switch {
+	case watchMode:
+		return nil, errors.New("--fail-if 不能与 --watch 同时使用")
+	case sampleDuration > 0:
+		return nil, errors.New("--fail-if 暂不支持 --sample-duration")
+	case podGroupBy != "":
+		return nil, errors.New("--fail-if 暂不支持 --group-by")
+	}
+	return parseThresholds(failIf, scope)
+}
+
+// 未通过阈值检查的一行
+type violation struct {
+	Name  string
+	Expr  string
+	Value float64
+}
+
+func nodeViolations(thresholds []threshold, nodeInfoList []nodeInfo) []violation {
+	var violations []violation
+	for _, info := range nodeInfoList {
+		for _, t := range thresholds {
+			if value, ok := nodeThresholdMetrics[t.Metric](info); ok && t.exceeded(value) {
+				violations = append(violations, violation{Name: info.NodeName, Expr: t.Expr, Value: value})
+			}
+		}
+	}
+	return violations
+}
+
+func podViolations(thresholds []threshold, podInfoList []*PodInfo) []violation {
+	var violations []violation
+	for _, podInfo := range podInfoList {
+		totals := podInfo.totals()
+		for _, t := range thresholds {
+			if value, ok := podThresholdMetrics[t.Metric](podInfo, totals); ok && t.exceeded(value) {
+				name := podInfo.PodResource.Namespace + "/" + podInfo.PodResource.PodName
+				violations = append(violations, violation{Name: name, Expr: t.Expr, Value: value})
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Name < violations[j].Name })
+	return violations
+}
+
+// 汇总未通过的行，main据此返回kube.ExitThresholdExceeded
+func thresholdError(violations []violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	for _, v := range violations {
+		fmt.Fprintf(&b, "\n  %s: %s (当前%.2f%%)", v.Name, v.Expr, v.Value)
+	}
+	return fmt.Errorf("%w: %d项%s", kube.ErrThresholdExceeded, len(violations), b.String())
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"metrics.k8s.io/kube"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		expr    string
+		scope   string
+		want    threshold
+		wantErr string
+	}{
+		{expr: "node.cpu.request-remaining<15", scope: "node", want: threshold{Metric: "cpu.request-remaining", Op: "<", Value: 15}},
+		{expr: "node.mem.util >= 90%", scope: "node", want: threshold{Metric: "mem.util", Op: ">=", Value: 90}},
+		{expr: "pod.mem.limit>90", scope: "pod", want: threshold{Metric: "mem.limit", Op: ">", Value: 90}},
+		{expr: "pod.mem.limit>90", scope: "node", wantErr: "node."},
+		{expr: "node.disk.util>90", scope: "node", wantErr: "未知的指标"},
+		{expr: "node.cpu.util>high", scope: "node", wantErr: "无效的百分比"},
+		{expr: "node.cpu.util=90", scope: "node", wantErr: "缺少比较符"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parseThreshold(tt.expr, tt.scope)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Expr = tt.expr
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholdViolations(t *testing.T) {
+	nodeThresholds, err := parseThresholds([]string{"node.cpu.request-remaining<15"}, "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := []nodeInfo{
+		{NodeName: "node-a", CPUTotal: 4000, CPUPercentage: 10},
+		{NodeName: "node-b", CPUTotal: 4000, CPUPercentage: 50},
+		{NodeName: "empty"}, // 无可分配资源的节点不参与判断
+	}
+	violations := nodeViolations(nodeThresholds, nodes)
+	if len(violations) != 1 || violations[0].Name != "node-a" {
+		t.Fatalf("node violations = %+v, want node-a only", violations)
+	}
+	err = thresholdError(violations)
+	if got := kube.ExitCode(err); got != kube.ExitThresholdExceeded {
+		t.Errorf("ExitCode = %d, want %d", got, kube.ExitThresholdExceeded)
+	}
+	if !strings.Contains(err.Error(), "node-a: node.cpu.request-remaining<15 (当前10.00%)") {
+		t.Errorf("summary missing violating node: %v", err)
+	}
+
+	podThresholds, err := parseThresholds([]string{"pod.cpu.request>90"}, "pod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pods := []*PodInfo{
+		newTestPodInfo("default", "hot", "node-a", "app", 100, 95),
+		newTestPodInfo("default", "idle", "node-a", "app", 100, 5),
+		newTestPodInfo("default", "best-effort", "node-a", "app", 0, 500),
+	}
+	violations = podViolations(podThresholds, pods)
+	if len(violations) != 1 || violations[0].Name != "default/hot" {
+		t.Fatalf("pod violations = %+v, want default/hot only", violations)
+	}
+	if err := thresholdError(podViolations(podThresholds, pods[1:])); err != nil {
+		t.Errorf("thresholdError = %v, want nil", err)
+	}
+}
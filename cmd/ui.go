@@ -0,0 +1,472 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	altScreenOn  = "\x1b[?1049h\x1b[?25l" // 切换到备用屏幕并隐藏光标
+	altScreenOff = "\x1b[?25h\x1b[?1049l"
+	uiHelp       = "↑/↓ 选择  Enter 下钻  ←/Esc 返回  s 切换排序  / 过滤  r 刷新  q 退出"
+)
+
+var (
+	nodeSortKeys = []string{"cpu.request", "cpu.util", "mem.request", "mem.util", "cpu.overcommit", "mem.overcommit"}
+	// 与pod/容器表格行Ratios的顺序一致
+	podSortKeys = []string{"cpu.request", "cpu.limit", "mem.request", "mem.limit"}
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Interactive view drilling down from nodes to pods to containers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if watchInterval <= 0 {
+			return fmt.Errorf("--interval 必须大于0: %s", watchInterval)
+		}
+		return runUI(cmd.Context(), newUIModel())
+	},
+	Args: cobra.NoArgs,
+}
+
+// 界面层级: 节点 -> 节点上的pod -> pod内的容器
+type uiLevel int
+
+const (
+	uiNodes uiLevel = iota
+	uiPods
+	uiContainers
+)
+
+type uiKeyKind int
+
+const (
+	keyRune uiKeyKind = iota
+	keyUp
+	keyDown
+	keyEnter
+	keyBack // ←
+	keyEsc
+	keyBackspace
+	keyCtrlC
+)
+
+type uiKey struct {
+	kind uiKeyKind
+	r    rune
+}
+
+// 按键处理结果
+type uiAction int
+
+const (
+	uiNone uiAction = iota
+	uiReload
+	uiQuit
+)
+
+// 交互界面的状态，数据加载通过函数注入，便于脱离终端测试
+type uiModel struct {
+	loadNodes func(ctx context.Context) ([]nodeInfo, error)
+	loadPods  func(ctx context.Context, node string) ([]*PodInfo, error)
+
+	level  uiLevel
+	node   string // 已选中的节点
+	podKey string // 已选中的pod，namespace/name
+	nodes  []nodeInfo
+	pods   []*PodInfo
+
+	sortBy    [3]int // 各层级当前排序键的下标
+	filter    string
+	filtering bool
+	cursor    int
+	status    string
+}
+
+func newUIModel() *uiModel {
+	return &uiModel{
+		loadNodes: func(ctx context.Context) ([]nodeInfo, error) {
+			return LoadNodeInfo(ctx, nodeSelector)
+		},
+		loadPods: loadNodePods,
+	}
+}
+
+// 列出节点上所有命名空间的pod及其用量，节点上没有pod时返回空列表
+// metrics API不支持按节点过滤，只查询这些pod所在的命名空间
+func loadNodePods(ctx context.Context, node string) ([]*PodInfo, error) {
+	resources, err := LoadK8sResource(ctx, metav1.NamespaceAll, Selector{Field: "spec.nodeName=" + node})
+	var noPods noPodsError
+	if errors.As(err, &noPods) {
+		return []*PodInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, resource := range resources {
+		if !seen[resource.Namespace] {
+			seen[resource.Namespace] = true
+			namespaces = append(namespaces, resource.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+
+	metrics := make(map[string]PodMetrics)
+	for _, ns := range namespaces {
+		nsMetrics, err := LoadK8sMetrics(ctx, ns, Selector{})
+		if err != nil {
+			return nil, err
+		}
+		for key, podMetrics := range nsMetrics {
+			metrics[key] = podMetrics
+		}
+	}
+	return CombinePodInfo(resources, metrics), nil
+}
+
+// 重新采集当前层级的数据，失败时保留上一次的结果并在状态栏提示
+func (m *uiModel) load(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	var err error
+	switch m.level {
+	case uiNodes:
+		var nodes []nodeInfo
+		if nodes, err = m.loadNodes(ctx); err == nil {
+			m.nodes = nodes
+		}
+	default:
+		var pods []*PodInfo
+		if pods, err = m.loadPods(ctx, m.node); err == nil {
+			m.pods = pods
+		}
+	}
+
+	if err != nil {
+		m.status = "刷新失败: " + err.Error()
+		return err
+	}
+	m.status = ""
+	return nil
+}
+
+func (m *uiModel) sortKey() string {
+	if m.level == uiNodes {
+		return nodeSortKeys[m.sortBy[m.level]]
+	}
+	return podSortKeys[m.sortBy[m.level]]
+}
+
+// 当前层级排序并按过滤条件筛选后的表格
+// 节点沿用node命令的排序规则，pod及容器按比例降序，压力最大的排在最前
+func (m *uiModel) table() ([]string, []tableRow) {
+	var (
+		header []string
+		rows   []tableRow
+	)
+	switch m.level {
+	case uiNodes:
+		SortNodeInfo(m.nodes, m.sortKey())
+		header, rows = nodeTableRows(m.nodes)
+	case uiPods:
+		header, rows = podTableRows(m.pods, true)
+		sortRowsByRatio(rows, m.sortBy[m.level])
+	case uiContainers:
+		header = containerHeader[2:]
+		if podInfo := m.selectedPod(); podInfo != nil {
+			rows = containerTableRows(podInfo)
+		}
+		sortRowsByRatio(rows, m.sortBy[m.level])
+	}
+
+	if m.filter == "" {
+		return header, rows
+	}
+	filtered := rows[:0]
+	for _, row := range rows {
+		if strings.Contains(strings.ToLower(row.Key), strings.ToLower(m.filter)) {
+			filtered = append(filtered, row)
+		}
+	}
+	return header, filtered
+}
+
+func sortRowsByRatio(rows []tableRow, index int) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Ratios[index] != rows[j].Ratios[index] {
+			return rows[i].Ratios[index] > rows[j].Ratios[index]
+		}
+		return rows[i].Key < rows[j].Key
+	})
+}
+
+func (m *uiModel) selectedPod() *PodInfo {
+	for _, podInfo := range m.pods {
+		if podInfo.PodResource.Namespace+"/"+podInfo.PodResource.PodName == m.podKey {
+			return podInfo
+		}
+	}
+	return nil
+}
+
+func (m *uiModel) handleKey(key uiKey) uiAction {
+	if key.kind == keyCtrlC {
+		return uiQuit
+	}
+
+	if m.filtering {
+		switch key.kind {
+		case keyRune:
+			m.filter += string(key.r)
+		case keyBackspace:
+			if _, size := utf8.DecodeLastRuneInString(m.filter); size > 0 {
+				m.filter = m.filter[:len(m.filter)-size]
+			}
+		case keyEnter:
+			m.filtering = false
+		case keyEsc:
+			m.filtering = false
+			m.filter = ""
+		}
+		m.cursor = 0
+		return uiNone
+	}
+
+	_, rows := m.table()
+	switch {
+	case key.kind == keyUp || key.r == 'k':
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case key.kind == keyDown || key.r == 'j':
+		if m.cursor < len(rows)-1 {
+			m.cursor++
+		}
+	case key.kind == keyEnter || key.r == 'l':
+		return m.drillDown(rows)
+	case key.kind == keyBack || key.kind == keyEsc || key.kind == keyBackspace || key.r == 'h':
+		return m.back()
+	case key.r == 's':
+		keys := podSortKeys
+		if m.level == uiNodes {
+			keys = nodeSortKeys
+		}
+		m.sortBy[m.level] = (m.sortBy[m.level] + 1) % len(keys)
+		m.cursor = 0
+	case key.r == '/':
+		m.filtering = true
+		m.filter = ""
+	case key.r == 'r':
+		return uiReload
+	case key.r == 'q':
+		return uiQuit
+	}
+	return uiNone
+}
+
+func (m *uiModel) drillDown(rows []tableRow) uiAction {
+	if m.cursor >= len(rows) {
+		return uiNone
+	}
+	key := rows[m.cursor].Key
+	m.cursor, m.filter = 0, ""
+	switch m.level {
+	case uiNodes:
+		m.level, m.node, m.pods = uiPods, key, nil
+		return uiReload
+	case uiPods:
+		m.level, m.podKey = uiContainers, key
+	}
+	return uiNone
+}
+
+func (m *uiModel) back() uiAction {
+	m.cursor, m.filter = 0, ""
+	switch m.level {
+	case uiContainers:
+		m.level = uiPods
+	case uiPods:
+		m.level = uiNodes
+		return uiReload
+	}
+	return uiNone
+}
+
+// 渲染一整屏，行数超出终端高度时滚动以保证选中行可见
+func (m *uiModel) render(w io.Writer, height int) {
+	header, rows := m.table()
+	if m.cursor >= len(rows) {
+		m.cursor = len(rows) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+
+	path := "节点"
+	if m.level >= uiPods {
+		path += " > " + m.node
+	}
+	if m.level == uiContainers {
+		path += " > " + m.podKey
+	}
+	fmt.Fprintf(w, "kubetop ui  %s  %s\n", path, time.Now().Format("15:04:05"))
+
+	filter := m.filter
+	if m.filtering {
+		filter += "_"
+	}
+	fmt.Fprintf(w, "排序: %s  过滤: %s  %s\n\n", m.sortKey(), filter, m.status)
+
+	// 标题、状态、空行、表头及帮助各占一行
+	visible := height - 5
+	if visible < 1 {
+		visible = 1
+	}
+	start := 0
+	if m.cursor >= visible {
+		start = m.cursor - visible + 1
+	}
+	end := start + visible
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	window := make([]tableRow, 0, end-start)
+	for i := start; i < end; i++ {
+		row := rows[i]
+		if i == m.cursor {
+			row.Cells = cursorCells(row.Cells)
+		}
+		window = append(window, row)
+	}
+	renderRows(w, header, window)
+	fmt.Fprint(w, uiHelp)
+}
+
+// 光标所在行反色显示，未启用颜色时在首列前加 > 标记
+func cursorCells(cells []string) []string {
+	if colorEnabled {
+		return highlightCells(cells)
+	}
+	marked := append([]string(nil), cells...)
+	if len(marked) > 0 {
+		marked[0] = "> " + marked[0]
+	}
+	return marked
+}
+
+// 将终端输入解析为按键
+func readKeys(r io.Reader, keys chan<- uiKey) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+func parseKeys(input []byte) []uiKey {
+	var keys []uiKey
+	for len(input) > 0 {
+		switch {
+		case bytes.HasPrefix(input, []byte("\x1b[A")):
+			keys, input = append(keys, uiKey{kind: keyUp}), input[3:]
+		case bytes.HasPrefix(input, []byte("\x1b[B")):
+			keys, input = append(keys, uiKey{kind: keyDown}), input[3:]
+		case bytes.HasPrefix(input, []byte("\x1b[C")):
+			keys, input = append(keys, uiKey{kind: keyEnter}), input[3:]
+		case bytes.HasPrefix(input, []byte("\x1b[D")):
+			keys, input = append(keys, uiKey{kind: keyBack}), input[3:]
+		case input[0] == 0x1b:
+			keys, input = append(keys, uiKey{kind: keyEsc}), input[1:]
+		case input[0] == 0x03:
+			keys, input = append(keys, uiKey{kind: keyCtrlC}), input[1:]
+		case input[0] == '\r' || input[0] == '\n':
+			keys, input = append(keys, uiKey{kind: keyEnter}), input[1:]
+		case input[0] == 0x7f || input[0] == 0x08:
+			keys, input = append(keys, uiKey{kind: keyBackspace}), input[1:]
+		default:
+			r, size := utf8.DecodeRune(input)
+			keys, input = append(keys, uiKey{kind: keyRune, r: r}), input[size:]
+		}
+	}
+	return keys
+}
+
+// 进入终端raw模式运行交互界面，直到按下q/Ctrl-C或收到终止信号
+func runUI(ctx context.Context, m *uiModel) error {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(out) {
+		return errors.New("ui 需要在交互式终端中运行")
+	}
+
+	// 首次采集在切换终端模式前完成，配置或权限错误直接返回
+	if err := m.load(ctx); err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(in, state)
+	os.Stdout.WriteString(altScreenOn)
+	defer os.Stdout.WriteString(altScreenOff)
+
+	keys := make(chan uiKey)
+	go readKeys(os.Stdin, keys)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		_, height, err := term.GetSize(out)
+		if err != nil {
+			height = 24
+		}
+		var buf bytes.Buffer
+		buf.WriteString(clearScreen)
+		m.render(&buf, height)
+		// raw模式下换行不会回到行首
+		if _, err := os.Stdout.WriteString(strings.ReplaceAll(buf.String(), "\n", "\r\n")); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.load(ctx)
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			switch m.handleKey(key) {
+			case uiQuit:
+				return nil
+			case uiReload:
+				m.load(ctx)
+			}
+		}
+	}
+}
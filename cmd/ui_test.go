@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"metrics.k8s.io/kube"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func TestUIModelDrillDown(t *testing.T) {
+	m := &uiModel{
+		loadNodes: func(ctx context.Context) ([]nodeInfo, error) {
+			return []nodeInfo{
+				{NodeName: "node-a", CPUPercentage: 80},
+				{NodeName: "node-b", CPUPercentage: 20},
+			}, nil
+		},
+		loadPods: func(ctx context.Context, node string) ([]*PodInfo, error) {
+			if node != "node-b" {
+				t.Fatalf("loadPods node = %s, want node-b", node)
+			}
+			web := newTestPodInfo("default", "web-1", node, "app", 100, 200)
+			web.PodResource.Containers["sidecar"] = &ContainerResource{Name: "sidecar", CPURequests: 100}
+			web.PodMetrics.Containers["sidecar"] = &ContainerMetrics{Name: "sidecar", CPUUsage: 10}
+			web.calculateContainerMetrics()
+			return []*PodInfo{web, newTestPodInfo("kube-system", "dns-1", node, "dns", 100, 50)}, nil
+		},
+	}
+	ctx := context.Background()
+	if err := m.load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// cpu.request剩余率升序，node-b排在最前
+	if m.handleKey(uiKey{kind: keyEnter}) != uiReload || m.node != "node-b" {
+		t.Fatalf("enter on nodes: level=%d node=%s", m.level, m.node)
+	}
+	if err := m.load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 按cpu用量/request降序，web-1排在最前；过滤后只剩dns-1
+	if _, rows := m.table(); rows[0].Key != "default/web-1" {
+		t.Fatalf("first pod = %s, want default/web-1", rows[0].Key)
+	}
+	for _, key := range parseKeys([]byte("/dns\r")) {
+		m.handleKey(key)
+	}
+	if _, rows := m.table(); len(rows) != 1 || rows[0].Key != "kube-system/dns-1" {
+		t.Fatalf("filtered rows = %v", rows)
+	}
+
+	// Esc清除过滤条件后进入web-1
+	for _, key := range parseKeys([]byte("/\x1b\r")) {
+		m.handleKey(key)
+	}
+	if m.level != uiContainers || m.podKey != "default/web-1" {
+		t.Fatalf("enter on pods: level=%d pod=%s", m.level, m.podKey)
+	}
+	_, rows := m.table()
+	if len(rows) != 2 || rows[0].Cells[0] != "app" {
+		t.Fatalf("container rows = %v", rows)
+	}
+
+	m.handleKey(uiKey{kind: keyBack})
+	if m.level != uiPods {
+		t.Fatalf("back from containers: level=%d", m.level)
+	}
+	if m.handleKey(uiKey{kind: keyRune, r: 'q'}) != uiQuit {
+		t.Fatal("q should quit")
+	}
+}
+
+func newTestPodInfo(ns, name, node, container string, cpuRequest, cpuUsage int64) *PodInfo {
+	podInfo := &PodInfo{
+		PodResource: PodResource{
+			Namespace:  ns,
+			PodName:    name,
+			NodeName:   node,
+			Containers: map[string]*ContainerResource{container: {Name: container, CPURequests: cpuRequest}},
+		},
+		PodMetrics: PodMetrics{
+			PodName:    name,
+			Containers: map[string]*ContainerMetrics{container: {Name: container, CPUUsage: cpuUsage}},
+		},
+	}
+	podInfo.calculateContainerMetrics()
+	podInfo.calculateTotalMetrics()
+	return podInfo
+}
+
+func TestLoadNodePods(t *testing.T) {
+	usage := map[string]corev1.ResourceList{"app": resourceList("100m", "100M")}
+	clients, err := kube.NewFakeClients(
+		[]runtime.Object{
+			newNode("node-a", "4", "8G"),
+			newNode("node-b", "4", "8G"),
+			newNode("node-empty", "4", "8G"),
+			newPod("shop", "web-1", "node-a", newContainer("app", resourceList("200m", "200M"), nil)),
+			newPod("infra", "proxy-1", "node-a", newContainer("app", resourceList("200m", "200M"), nil)),
+			newPod("batch", "job-1", "node-b", newContainer("app", resourceList("200m", "200M"), nil)),
+		},
+		[]metricsv1beta1.PodMetrics{
+			newPodMetrics("shop", "web-1", usage),
+			newPodMetrics("infra", "proxy-1", usage),
+			newPodMetrics("batch", "job-1", usage),
+		},
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := kube.WithClients(context.Background(), clients)
+
+	pods, err := loadNodePods(ctx, "node-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 2 {
+		t.Fatalf("got %d pods, want 2", len(pods))
+	}
+
+	// 只查询节点上pod所在命名空间的metrics
+	var namespaces []string
+	for _, action := range clients.Metrics.(*metricsfake.Clientset).Actions() {
+		namespaces = append(namespaces, action.GetNamespace())
+	}
+	sort.Strings(namespaces)
+	if want := []string{"infra", "shop"}; !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("metrics listed in %v, want %v", namespaces, want)
+	}
+
+	pods, err = loadNodePods(ctx, "node-empty")
+	if err != nil {
+		t.Fatalf("node without pods should not be an error: %v", err)
+	}
+	if pods == nil || len(pods) != 0 {
+		t.Errorf("pods = %v, want empty list", pods)
+	}
+}
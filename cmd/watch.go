@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"metrics.k8s.io/kube"
+)
+
+const (
+	clearScreen = "\x1b[H\x1b[2J" // 光标移至左上角并清屏
+	highlightOn = "\x1b[7m"       // 反色显示变化较大的行
+	colorReset  = "\x1b[0m"
+)
+
+var (
+	requestTimeout = 5 * time.Second // 单次采集的超时时间
+
+	watchMode      bool
+	watchInterval  time.Duration
+	watchThreshold float64 // 比例变化超过该值(百分点)的行高亮显示
+)
+
+// 表格中的一行，Key用于与上一次刷新的结果对应，Ratios为参与变化判断的比例
+type tableRow struct {
+	Key    string
+	Ratios []float64
+	Cells  []string
+}
+
+// 采集一次并生成表格，watch模式下周期调用
+type tableView func(ctx context.Context) ([]string, []tableRow, error)
+
+func validateWatch() error {
+	if !watchMode {
+		return nil
+	}
+	if isStructuredOutput(outputFormat) {
+		return errors.New("--watch 不能与 -o 同时使用")
+	}
+	if watchInterval <= 0 {
+		return fmt.Errorf("--interval 必须大于0: %s", watchInterval)
+	}
+	return nil
+}
+
+func renderRows(w io.Writer, header []string, rows []tableRow) {
+	cells := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		cells = append(cells, row.Cells)
+	}
+
+	table := kube.NewTableWriter(w)
+	table.SetHeader(header)
+	table.AppendBulk(cells)
+	table.Render()
+}
+
+// 周期执行view并原地刷新，直到ctx被取消(Ctrl-C)
+func watchTable(ctx context.Context, view tableView) error {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var (
+		previous map[string][]float64
+		header   []string
+		rows     []tableRow
+		status   string
+	)
+	for {
+		tickCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		newHeader, newRows, err := view(tickCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			highlightChanges(newRows, previous)
+			previous = rowRatios(newRows)
+			header, rows, status = newHeader, newRows, ""
+		case ctx.Err() != nil:
+			return nil
+		case kube.ExitCode(err) != kube.ExitError:
+			// 权限、命名空间等错误重试也无法恢复
+			return err
+		default:
+			// 临时错误保留上一次的结果，下次刷新重试
+			status = "刷新失败: " + err.Error()
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString(clearScreen)
+		fmt.Fprintf(&buf, "每%s刷新  %s  高亮: 比例变化超过%.0f个百分点  Ctrl-C退出\n", watchInterval, time.Now().Format("15:04:05"), watchThreshold)
+		if status != "" {
+			fmt.Fprintln(&buf, status)
+		}
+		buf.WriteString("\n")
+		if header != nil {
+			renderRows(&buf, header, rows)
+		}
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// 与上一次刷新相比，任一比例变化超过阈值或新出现的行整行高亮，
+// 未启用颜色(--color=never、NO_COLOR或非终端)时不高亮
+func highlightChanges(rows []tableRow, previous map[string][]float64) {
+	if previous == nil || !colorEnabled {
+		return
+	}
+	for i := range rows {
+		if !ratiosChanged(previous[rows[i].Key], rows[i].Ratios) {
+			continue
+		}
+		rows[i].Cells = highlightCells(rows[i].Cells)
+	}
+}
+
+// 整行反色显示，返回新的切片
+func highlightCells(cells []string) []string {
+	highlighted := make([]string, len(cells))
+	for i, cell := range cells {
+		highlighted[i] = highlightOn + cell + colorReset
+	}
+	return highlighted
+}
+
+func ratiosChanged(before, after []float64) bool {
+	if len(before) != len(after) {
+		return true
+	}
+	for i := range after {
+		if math.Abs(after[i]-before[i]) >= watchThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+func rowRatios(rows []tableRow) map[string][]float64 {
+	ratios := make(map[string][]float64, len(rows))
+	for _, row := range rows {
+		ratios[row.Key] = row.Ratios
+	}
+	return ratios
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightChanges(t *testing.T) {
+	defer func(threshold float64, enabled bool) {
+		watchThreshold, colorEnabled = threshold, enabled
+	}(watchThreshold, colorEnabled)
+	watchThreshold, colorEnabled = 10, true
+	previous := map[string][]float64{
+		"node-a": {50, 20},
+		"node-b": {50, 20},
+	}
+	rows := []tableRow{
+		{Key: "node-a", Ratios: []float64{55, 25}, Cells: []string{"node-a"}},
+		{Key: "node-b", Ratios: []float64{50, 35}, Cells: []string{"node-b"}},
+		{Key: "node-c", Ratios: []float64{10, 10}, Cells: []string{"node-c"}},
+	}
+
+	highlightChanges(rows, previous)
+
+	want := map[string]bool{"node-a": false, "node-b": true, "node-c": true}
+	for _, row := range rows {
+		if got := strings.HasPrefix(row.Cells[0], highlightOn); got != want[row.Key] {
+			t.Errorf("%s highlighted = %v, want %v", row.Key, got, want[row.Key])
+		}
+	}
+}
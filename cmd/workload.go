@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"metrics.k8s.io/kube"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const groupByOwner = "owner"
+
+var (
+	podGroupBy     string
+	workloadHeader = []string{"类型", "名称", "副本数", "cpu request|limit|usage", "cpu用量/request占比", "cpu用量/limit占比", "内存 request|limit|usage", "内存用量/request占比", "内存用量/limit占比"}
+)
+
+var workloadCmd = &cobra.Command{
+	Use:   "workload",
+	Short: "Print the usage of workloads in namespace",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(outputFormat); err != nil {
+			return err
+		}
+		if err := validateWatch(); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), requestTimeout)
+		defer cancel()
+		ns, nslist, err := targetNamespace(ctx)
+		if err != nil {
+			return err
+		}
+		if watchMode {
+			return watchTable(cmd.Context(), workloadView(ns, nslist))
+		}
+		return PrintWorkloads(ctx, ns, nslist)
+	},
+	Args:    cobra.NoArgs,
+	Aliases: []string{"workloads", "wl"},
+}
+
+// 工作负载标识，Kind为Deployment、StatefulSet、DaemonSet、Job、CronJob等，无控制者的pod为Pod
+type workloadKey struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+// 工作负载级别的资源汇总，Replicas为参与汇总的pod数
+type Workload struct {
+	workloadKey
+	Replicas int
+	podTotals
+	CPUUsageToRequestRatio float64
+	CPUUsageToLimitsRatio  float64
+	MemUsageToRequestRatio float64
+	MemUsageToLimitsRatio  float64
+}
+
+// ReplicaSet -> Deployment、Job -> CronJob 的上级控制者索引
+type ownerIndex map[workloadKey]workloadKey
+
+// 列出命名空间下的ReplicaSet及Job，记录它们的控制者
+func LoadOwnerIndex(ctx context.Context, namespace string) (ownerIndex, error) {
+	client, err := kube.GetK8sClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(ownerIndex)
+	replicaSets, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return nil, kube.Error(err, "列出ReplicaSet失败")
+	}
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if owner := metav1.GetControllerOfNoCopy(rs); owner != nil {
+			index[workloadKey{rs.Namespace, "ReplicaSet", rs.Name}] = workloadKey{rs.Namespace, owner.Kind, owner.Name}
+		}
+	}
+
+	jobs, err := client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return nil, kube.Error(err, "列出Job失败")
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if owner := metav1.GetControllerOfNoCopy(job); owner != nil {
+			index[workloadKey{job.Namespace, "Job", job.Name}] = workloadKey{job.Namespace, owner.Kind, owner.Name}
+		}
+	}
+	return index, nil
+}
+
+// 沿ownerReferences找到pod所属的顶层工作负载
+func (index ownerIndex) resolve(pod PodResource) workloadKey {
+	if pod.OwnerKind == "" {
+		return workloadKey{pod.Namespace, "Pod", pod.PodName}
+	}
+	key := workloadKey{pod.Namespace, pod.OwnerKind, pod.OwnerName}
+	if owner, ok := index[key]; ok {
+		return owner
+	}
+	return key
+}
+
+// 按工作负载汇总pod的request、limit及用量
+func AggregateWorkloads(podInfoList []*PodInfo, index ownerIndex) []*Workload {
+	workloads := make(map[workloadKey]*Workload)
+	for _, podInfo := range podInfoList {
+		key := index.resolve(podInfo.PodResource)
+		workload, ok := workloads[key]
+		if !ok {
+			workload = &Workload{workloadKey: key}
+			workloads[key] = workload
+		}
+
+		t := podInfo.totals()
+		workload.Replicas++
+		workload.CPURequests += t.CPURequests
+		workload.CPULimits += t.CPULimits
+		workload.CPUUsage += t.CPUUsage
+		workload.MemRequests += t.MemRequests
+		workload.MemLimits += t.MemLimits
+		workload.MemUsage += t.MemUsage
+	}
+
+	workloadList := make([]*Workload, 0, len(workloads))
+	for _, workload := range workloads {
+		workload.CPUUsageToRequestRatio = calculateRatio(workload.CPUUsage, workload.CPURequests)
+		workload.CPUUsageToLimitsRatio = calculateRatio(workload.CPUUsage, workload.CPULimits)
+		workload.MemUsageToRequestRatio = calculateRatio(workload.MemUsage, workload.MemRequests)
+		workload.MemUsageToLimitsRatio = calculateRatio(workload.MemUsage, workload.MemLimits)
+		workloadList = append(workloadList, workload)
+	}
+	return workloadList
+}
+
+// 按排序规则升序排列，比例相同时按命名空间/类型/名称排列
+func SortWorkloads(workloadList []*Workload, sortBy string) error {
+	var ratio func(*Workload) float64
+	switch sortBy {
+	case "cpu.request":
+		ratio = func(w *Workload) float64 { return w.CPUUsageToRequestRatio }
+	case "mem.request":
+		ratio = func(w *Workload) float64 { return w.MemUsageToRequestRatio }
+	case "cpu.limit":
+		ratio = func(w *Workload) float64 { return w.CPUUsageToLimitsRatio }
+	case "mem.limit":
+		ratio = func(w *Workload) float64 { return w.MemUsageToLimitsRatio }
+	default:
+		return fmt.Errorf("未知的排序选项: %s", sortBy)
+	}
+
+	sort.Slice(workloadList, func(i, j int) bool {
+		if ratio(workloadList[i]) != ratio(workloadList[j]) {
+			return ratio(workloadList[i]) < ratio(workloadList[j])
+		}
+		return fmt.Sprint(workloadList[i].workloadKey) < fmt.Sprint(workloadList[j].workloadKey)
+	})
+	return nil
+}
+
+func PrintWorkloads(ctx context.Context, namespace string, nslist []string) error {
+	workloadList, err := LoadWorkloads(ctx, namespace, nslist)
+	if err != nil {
+		return err
+	}
+	if isStructuredOutput(outputFormat) {
+		return writeWorkloadRecords(os.Stdout, outputFormat, workloadList)
+	}
+
+	header, rows := workloadTableRows(workloadList, namespace == metav1.NamespaceAll)
+	renderRows(os.Stdout, header, rows)
+	return nil
+}
+
+// 采集pod及其控制者，按工作负载汇总并排序
+func LoadWorkloads(ctx context.Context, namespace string, nslist []string) ([]*Workload, error) {
+	podInfoList, err := loadCombinedPodInfo(ctx, namespace, nslist)
+	if err != nil {
+		return nil, err
+	}
+	index, err := LoadOwnerIndex(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	workloadList := AggregateWorkloads(podInfoList, index)
+	if err := SortWorkloads(workloadList, podSortBy); err != nil {
+		return nil, err
+	}
+	return workloadList, nil
+}
+
+func workloadTableRows(workloadList []*Workload, showNamespace bool) ([]string, []tableRow) {
+	rows := make([]tableRow, 0, len(workloadList))
+	for _, workload := range workloadList {
+		result := []string{
+			workload.Kind,
+			workload.Name,
+			fmt.Sprint(workload.Replicas),
+			formatResourceUsage(workload.CPURequests, workload.CPULimits, workload.CPUUsage, "CPU"),
+			ratioCell("pod.cpu.request", workload.CPUUsageToRequestRatio),
+			ratioCell("pod.cpu.limit", workload.CPUUsageToLimitsRatio),
+			formatResourceUsage(workload.MemRequests, workload.MemLimits, workload.MemUsage, "Memory"),
+			ratioCell("pod.mem.request", workload.MemUsageToRequestRatio),
+			ratioCell("pod.mem.limit", workload.MemUsageToLimitsRatio),
+		}
+		if showNamespace {
+			result = append([]string{workload.Namespace}, result...)
+		}
+		rows = append(rows, tableRow{
+			Key:    workload.Namespace + "/" + workload.Kind + "/" + workload.Name,
+			Ratios: []float64{workload.CPUUsageToRequestRatio, workload.CPUUsageToLimitsRatio, workload.MemUsageToRequestRatio, workload.MemUsageToLimitsRatio},
+			Cells:  result,
+		})
+	}
+
+	header := workloadHeader
+	if showNamespace {
+		header = append([]string{namespaceHeader}, header...)
+	}
+	return header, rows
+}
+
+// watch模式下周期刷新的工作负载视图
+func workloadView(namespace string, nslist []string) tableView {
+	return func(ctx context.Context) ([]string, []tableRow, error) {
+		workloadList, err := LoadWorkloads(ctx, namespace, nslist)
+		if err != nil {
+			return nil, nil, err
+		}
+		header, rows := workloadTableRows(workloadList, namespace == metav1.NamespaceAll)
+		return header, rows, nil
+	}
+}
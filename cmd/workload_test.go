@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func ownedPod(name, ownerKind, ownerName, cpu string) *corev1.Pod {
+	pod := newPod("default", name, "node-a", newContainer("app", resourceList(cpu, "100M"), nil))
+	if ownerKind != "" {
+		pod.OwnerReferences = controllerRef(ownerKind, ownerName)
+	}
+	return pod
+}
+
+func TestAggregateWorkloads(t *testing.T) {
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-7d9f", OwnerReferences: controllerRef("Deployment", "web")}}
+	orphanRS := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "legacy-rs"}}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "backup-2760", OwnerReferences: controllerRef("CronJob", "backup")}}
+
+	pods := []*corev1.Pod{
+		ownedPod("web-7d9f-a", "ReplicaSet", "web-7d9f", "100m"),
+		ownedPod("web-7d9f-b", "ReplicaSet", "web-7d9f", "100m"),
+		ownedPod("legacy-rs-a", "ReplicaSet", "legacy-rs", "100m"),
+		ownedPod("db-0", "StatefulSet", "db", "200m"),
+		ownedPod("agent-x1", "DaemonSet", "agent", "50m"),
+		ownedPod("backup-2760-z", "Job", "backup-2760", "100m"),
+		ownedPod("debug", "", "", "100m"),
+	}
+
+	objects := []runtime.Object{rs, orphanRS, job}
+	var podMetrics []metricsv1beta1.PodMetrics
+	for _, pod := range pods {
+		objects = append(objects, pod)
+		podMetrics = append(podMetrics, newPodMetrics("default", pod.Name, map[string]corev1.ResourceList{"app": resourceList("50m", "50M")}))
+	}
+	ctx := fakeContext(t, objects, podMetrics, nil)
+
+	index, err := LoadOwnerIndex(ctx, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workloadList := AggregateWorkloads(loadPodInfo(t, ctx, "default"), index)
+
+	tests := []struct {
+		key        workloadKey
+		replicas   int
+		cpuRequest int64
+		cpuRatio   float64
+	}{
+		{workloadKey{"default", "Deployment", "web"}, 2, 200, 50},
+		{workloadKey{"default", "ReplicaSet", "legacy-rs"}, 1, 100, 50},
+		{workloadKey{"default", "StatefulSet", "db"}, 1, 200, 25},
+		{workloadKey{"default", "DaemonSet", "agent"}, 1, 50, 100},
+		{workloadKey{"default", "CronJob", "backup"}, 1, 100, 50},
+		{workloadKey{"default", "Pod", "debug"}, 1, 100, 50},
+	}
+	if len(workloadList) != len(tests) {
+		t.Fatalf("got %d workloads, want %d", len(workloadList), len(tests))
+	}
+
+	byKey := make(map[workloadKey]*Workload)
+	for _, workload := range workloadList {
+		byKey[workload.workloadKey] = workload
+	}
+	for _, tt := range tests {
+		t.Run(tt.key.Kind+"/"+tt.key.Name, func(t *testing.T) {
+			workload, ok := byKey[tt.key]
+			if !ok {
+				t.Fatalf("workload %+v missing", tt.key)
+			}
+			if workload.Replicas != tt.replicas || workload.CPURequests != tt.cpuRequest || !almostEqual(workload.CPUUsageToRequestRatio, tt.cpuRatio) {
+				t.Errorf("got replicas=%d cpuRequest=%d ratio=%.2f, want %d/%d/%.2f",
+					workload.Replicas, workload.CPURequests, workload.CPUUsageToRequestRatio, tt.replicas, tt.cpuRequest, tt.cpuRatio)
+			}
+		})
+	}
+
+	if err := SortWorkloads(workloadList, "cpu.request"); err != nil {
+		t.Fatal(err)
+	}
+	if workloadList[0].Name != "db" || workloadList[len(workloadList)-1].Name != "agent" {
+		t.Errorf("unexpected order: first=%s last=%s", workloadList[0].Name, workloadList[len(workloadList)-1].Name)
+	}
+}
@@ -1,34 +1,135 @@
 package kube
 
 import (
+	"context"
+	"sync"
+
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
-var (
-	k8sClient     *kubernetes.Clientset
-	metricsClient *metrics.Clientset
-)
+// ClientOptions 对应根命令上的 kubeconfig 相关选项
+type ClientOptions struct {
+	Kubeconfig string   // --kubeconfig，为空时按 KUBECONFIG 环境变量及 ~/.kube/config 合并加载
+	Context    string   // --context
+	Cluster    string   // --cluster
+	User       string   // --user
+	As         string   // --as
+	AsGroups   []string // --as-group
+}
+
+// ClientFactory 按需构建并缓存 rest 配置及客户端
+type ClientFactory struct {
+	opts ClientOptions
+
+	once          sync.Once
+	config        *rest.Config
+	k8sClient     kubernetes.Interface
+	metricsClient metrics.Interface
+	err           error
+}
+
+// Clients 为各命令提供访问集群的接口，测试时可通过WithClients注入fake客户端
+type Clients struct {
+	K8s     kubernetes.Interface
+	Metrics metrics.Interface
+}
+
+type clientsKey struct{}
+
+var defaultFactory = NewClientFactory(ClientOptions{})
+
+func NewClientFactory(opts ClientOptions) *ClientFactory {
+	return &ClientFactory{opts: opts}
+}
 
-func init() {
-	//生成config配置
-	config, err := clientcmd.BuildConfigFromFlags("", clientcmd.RecommendedHomeFile)
-	Error(err, "构建kubeconfig配置文件失败")
+// SetClientOptions 替换默认工厂，需在首次获取客户端之前调用
+func SetClientOptions(opts ClientOptions) {
+	defaultFactory = NewClientFactory(opts)
+}
+
+// RESTConfig 生成rest配置：优先使用kubeconfig，未找到任何配置时回退到集群内配置
+func (f *ClientFactory) RESTConfig() (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = f.opts.Kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: f.opts.Context,
+	}
+	overrides.Context.Cluster = f.opts.Cluster
+	overrides.Context.AuthInfo = f.opts.User
+	overrides.AuthInfo.Impersonate = f.opts.As
+	overrides.AuthInfo.ImpersonateGroups = f.opts.AsGroups
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil && clientcmd.IsEmptyConfig(err) && f.opts.Kubeconfig == "" {
+		// 以CronJob等方式运行在pod中时没有kubeconfig
+		config, err = rest.InClusterConfig()
+		if err == nil {
+			config.Impersonate.UserName = f.opts.As
+			config.Impersonate.Groups = f.opts.AsGroups
+		}
+	}
+	return config, err
+}
+
+func (f *ClientFactory) init() error {
+	f.once.Do(func() {
+		//生成config配置
+		config, err := f.RESTConfig()
+		if err != nil {
+			f.err = Error(err, "构建kubeconfig配置文件失败")
+			return
+		}
+		f.config = config
 
-	//metrics-client
-	metricsClient, err = metrics.NewForConfig(config)
-	Error(err,"构建metrics客户端失败")
+		//metrics-client
+		f.metricsClient, err = metrics.NewForConfig(config)
+		if err != nil {
+			f.err = Error(err, "构建metrics客户端失败")
+			return
+		}
+
+		//common-client
+		f.k8sClient, err = kubernetes.NewForConfig(config)
+		if err != nil {
+			f.err = Error(err, "构建rest客户端失败")
+		}
+	})
+	return f.err
+}
+
+func (f *ClientFactory) K8sClient() (kubernetes.Interface, error) {
+	if err := f.init(); err != nil {
+		return nil, err
+	}
+	return f.k8sClient, nil
+}
+
+func (f *ClientFactory) MetricsClient() (metrics.Interface, error) {
+	if err := f.init(); err != nil {
+		return nil, err
+	}
+	return f.metricsClient, nil
+}
 
-	//common-client
-	k8sClient, err = kubernetes.NewForConfig(config)
-	Error(err,"构建rest客户端失败")
+// WithClients 返回携带指定客户端的context，之后的GetK8sClient/GetMetricsClient优先使用它们
+func WithClients(ctx context.Context, clients Clients) context.Context {
+	return context.WithValue(ctx, clientsKey{}, clients)
 }
 
-func GetK8sClient() *kubernetes.Clientset {
-	return k8sClient
+func GetK8sClient(ctx context.Context) (kubernetes.Interface, error) {
+	if clients, ok := ctx.Value(clientsKey{}).(Clients); ok && clients.K8s != nil {
+		return clients.K8s, nil
+	}
+	return defaultFactory.K8sClient()
 }
 
-func GetMetricsClient() *metrics.Clientset {
-	return metricsClient
+func GetMetricsClient(ctx context.Context) (metrics.Interface, error) {
+	if clients, ok := ctx.Value(clientsKey{}).(Clients); ok && clients.Metrics != nil {
+		return clients.Metrics, nil
+	}
+	return defaultFactory.MetricsClient()
 }
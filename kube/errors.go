@@ -0,0 +1,65 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var (
+	ErrForbidden          = errors.New("权限不足")
+	ErrNamespaceNotFound  = errors.New("命名空间不存在")
+	ErrMetricsUnavailable = errors.New("metrics API不可用，请确认metrics-server已部署")
+	ErrThresholdExceeded  = errors.New("未通过--fail-if阈值检查")
+)
+
+// 进程退出码，便于脚本区分失败原因
+const (
+	ExitOK                 = 0
+	ExitError              = 1
+	ExitThresholdExceeded  = 2
+	ExitForbidden          = 3
+	ExitNamespaceNotFound  = 4
+	ExitMetricsUnavailable = 5
+)
+
+// 为err附加说明，并将RBAC拒绝归类为ErrForbidden
+func Error(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	if apierrors.IsForbidden(err) {
+		return fmt.Errorf("%s: %w: %w", msg, ErrForbidden, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// 与Error相同，另外将metrics.k8s.io未注册或不可用的情况归类为ErrMetricsUnavailable
+func MetricsError(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+		return fmt.Errorf("%s: %w: %w", msg, ErrMetricsUnavailable, err)
+	}
+	return Error(err, msg)
+}
+
+// 根据错误类型返回进程退出码
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrThresholdExceeded):
+		return ExitThresholdExceeded
+	case errors.Is(err, ErrForbidden):
+		return ExitForbidden
+	case errors.Is(err, ErrNamespaceNotFound):
+		return ExitNamespaceNotFound
+	case errors.Is(err, ErrMetricsUnavailable):
+		return ExitMetricsUnavailable
+	default:
+		return ExitError
+	}
+}
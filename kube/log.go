@@ -17,8 +17,8 @@ const (
 
 
 func init() {
+	// 仅注册klog的flag，命令行参数统一交由cobra解析
 	klog.InitFlags(nil)
-	flag.Parse()
 
 	klog.SetOutput(os.Stdout)
 }
@@ -37,13 +37,6 @@ func Warning(err error, msg string) {
 	}
 }
 
-func Error(err error, msg string) {
-	if err != nil {
-		klog.ErrorDepth(1, msg, err)
-		os.Exit(1)
-	}
-}
-
 func SetLogLevel(level LogLevel) {
 	switch level {
 	case INFO:
@@ -0,0 +1,131 @@
+package kube
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// fake metrics客户端按kind推断出的资源名与List使用的资源名(pods/nodes)不一致，需显式指定
+var (
+	podMetricsGVR  = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+	nodeMetricsGVR = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"}
+)
+
+// 快照回放以client-go的fake客户端承载快照内容，使回放与实时集群共用同一套加载逻辑。
+// fake客户端只按标签过滤，字段选择器按下面的白名单在客户端过滤，
+// 只包含kubetop自身用到的及语义为简单等值比较的字段，其它字段直接报错，不模拟apiserver的语义
+type replayFields struct {
+	empty    runtime.Object // 用于取得支持的字段名
+	fieldsOf func(runtime.Object) fields.Set
+}
+
+var replaySelectableFields = map[string]replayFields{
+	"pods": {&corev1.Pod{}, func(obj runtime.Object) fields.Set {
+		pod := obj.(*corev1.Pod)
+		return fields.Set{
+			"metadata.name":      pod.Name,
+			"metadata.namespace": pod.Namespace,
+			"spec.nodeName":      pod.Spec.NodeName,
+			"status.phase":       string(pod.Status.Phase),
+		}
+	}},
+	"nodes": {&corev1.Node{}, func(obj runtime.Object) fields.Set {
+		node := obj.(*corev1.Node)
+		return fields.Set{
+			"metadata.name":      node.Name,
+			"spec.unschedulable": strconv.FormatBool(node.Spec.Unschedulable),
+		}
+	}},
+}
+
+// Clients 返回以快照内容为数据的只读客户端，与实时集群的客户端可互换
+func (s *Snapshot) Clients() (Clients, error) {
+	objects := make([]runtime.Object, 0, len(s.Namespaces)+len(s.Nodes)+len(s.Pods)+len(s.ReplicaSets)+len(s.Jobs))
+	for i := range s.Namespaces {
+		objects = append(objects, &s.Namespaces[i])
+	}
+	for i := range s.Nodes {
+		objects = append(objects, &s.Nodes[i])
+	}
+	for i := range s.Pods {
+		objects = append(objects, &s.Pods[i])
+	}
+	for i := range s.ReplicaSets {
+		objects = append(objects, &s.ReplicaSets[i])
+	}
+	for i := range s.Jobs {
+		objects = append(objects, &s.Jobs[i])
+	}
+	return NewFakeClients(objects, s.PodMetrics, s.NodeMetrics)
+}
+
+// NewFakeClients 返回以给定对象及metrics为数据的fake客户端，供快照回放及测试使用
+func NewFakeClients(objects []runtime.Object, podMetrics []metricsv1beta1.PodMetrics, nodeMetrics []metricsv1beta1.NodeMetrics) (Clients, error) {
+	metricsClient := metricsfake.NewSimpleClientset()
+	for i := range podMetrics {
+		if err := metricsClient.Tracker().Create(podMetricsGVR, &podMetrics[i], podMetrics[i].Namespace); err != nil {
+			return Clients{}, err
+		}
+	}
+	for i := range nodeMetrics {
+		if err := metricsClient.Tracker().Create(nodeMetricsGVR, &nodeMetrics[i], ""); err != nil {
+			return Clients{}, err
+		}
+	}
+
+	client := fake.NewSimpleClientset(objects...)
+	for resource, selectable := range replaySelectableFields {
+		client.PrependReactor("list", resource, fieldSelectorReactor(client.Tracker(), resource, selectable))
+	}
+	return Clients{K8s: client, Metrics: metricsClient}, nil
+}
+
+// 带字段选择器的List在这里处理，不支持的字段不论是否有数据都返回错误
+func fieldSelectorReactor(tracker k8stesting.ObjectTracker, resource string, selectable replayFields) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		list, ok := action.(k8stesting.ListActionImpl)
+		if !ok {
+			return false, nil, nil
+		}
+		selector := list.GetListRestrictions().Fields
+		if selector == nil || selector.Empty() {
+			return false, nil, nil
+		}
+		supported := selectable.fieldsOf(selectable.empty)
+		for _, requirement := range selector.Requirements() {
+			if !supported.Has(requirement.Field) {
+				return true, nil, apierrors.NewBadRequest(fmt.Sprintf("快照回放不支持%s的字段选择器: %s", resource, requirement.Field))
+			}
+		}
+
+		obj, err := tracker.List(list.GetResource(), list.GetKind(), list.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return true, nil, err
+		}
+		var matched []runtime.Object
+		for _, item := range items {
+			if selector.Matches(selectable.fieldsOf(item)) {
+				matched = append(matched, item)
+			}
+		}
+		if err := meta.SetList(obj, matched); err != nil {
+			return true, nil, err
+		}
+		return true, obj, nil
+	}
+}
@@ -0,0 +1,135 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+const (
+	snapshotAPIVersion = "kubetop/v1"
+	snapshotKind       = "Snapshot"
+)
+
+// Snapshot 为某一时刻kubetop所需的集群状态，可离线回放
+type Snapshot struct {
+	APIVersion  string                       `json:"apiVersion"`
+	Kind        string                       `json:"kind"`
+	Timestamp   metav1.Time                  `json:"timestamp"`
+	Namespaces  []corev1.Namespace           `json:"namespaces"`
+	Nodes       []corev1.Node                `json:"nodes"`
+	Pods        []corev1.Pod                 `json:"pods"`
+	ReplicaSets []appsv1.ReplicaSet          `json:"replicaSets"`
+	Jobs        []batchv1.Job                `json:"jobs"`
+	NodeMetrics []metricsv1beta1.NodeMetrics `json:"nodeMetrics"`
+	PodMetrics  []metricsv1beta1.PodMetrics  `json:"podMetrics"`
+}
+
+// CaptureSnapshot 采集所有命名空间下的资源及metrics
+func CaptureSnapshot(ctx context.Context) (*Snapshot, error) {
+	client, err := GetK8sClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metricsClient, err := GetMetricsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{APIVersion: snapshotAPIVersion, Kind: snapshotKind, Timestamp: metav1.Now()}
+	opts := metav1.ListOptions{ResourceVersion: "0"}
+
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, opts)
+	if err != nil {
+		return nil, Error(err, "列出命名空间失败")
+	}
+	snapshot.Namespaces = namespaces.Items
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, opts)
+	if err != nil {
+		return nil, Error(err, "列出节点失败")
+	}
+	snapshot.Nodes = nodes.Items
+
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, Error(err, "列出所有Pod失败")
+	}
+	snapshot.Pods = pods.Items
+
+	replicaSets, err := client.AppsV1().ReplicaSets(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, Error(err, "列出ReplicaSet失败")
+	}
+	snapshot.ReplicaSets = replicaSets.Items
+
+	jobs, err := client.BatchV1().Jobs(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, Error(err, "列出Job失败")
+	}
+	snapshot.Jobs = jobs.Items
+
+	nodeMetrics, err := metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, opts)
+	if err != nil {
+		return nil, MetricsError(err, "列出所有节点metrics指标失败")
+	}
+	snapshot.NodeMetrics = nodeMetrics.Items
+
+	podMetrics, err := metricsClient.MetricsV1beta1().PodMetricses(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, MetricsError(err, "获取所有pod的指标失败")
+	}
+	snapshot.PodMetrics = podMetrics.Items
+
+	snapshot.stripManagedFields()
+	return snapshot, nil
+}
+
+// managedFields与kubetop无关且占据快照的大部分体积
+func (s *Snapshot) stripManagedFields() {
+	for i := range s.Namespaces {
+		s.Namespaces[i].ManagedFields = nil
+	}
+	for i := range s.Nodes {
+		s.Nodes[i].ManagedFields = nil
+	}
+	for i := range s.Pods {
+		s.Pods[i].ManagedFields = nil
+	}
+	for i := range s.ReplicaSets {
+		s.ReplicaSets[i].ManagedFields = nil
+	}
+	for i := range s.Jobs {
+		s.Jobs[i].ManagedFields = nil
+	}
+}
+
+func (s *Snapshot) Save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("解析快照文件 %s 失败: %w", path, err)
+	}
+	if snapshot.APIVersion != snapshotAPIVersion || snapshot.Kind != snapshotKind {
+		return nil, fmt.Errorf("%s 不是kubetop快照文件: %s/%s", path, snapshot.APIVersion, snapshot.Kind)
+	}
+	return snapshot, nil
+}
@@ -1,6 +1,7 @@
 package kube
 
 import (
+	"io"
 	"os"
 
 	"github.com/olekukonko/tablewriter"
@@ -11,8 +12,13 @@ type table struct {
 }
 
 func NewTable() *table {
+	return NewTableWriter(os.Stdout)
+}
+
+// 输出到指定writer，watch模式下先写入缓冲区再整屏刷新
+func NewTableWriter(w io.Writer) *table {
 	table := &table{
-		Table: tablewriter.NewWriter(os.Stdout),
+		Table: tablewriter.NewWriter(w),
 	}
 	table.SetAutoWrapText(false)
 	table.SetAutoFormatHeaders(true)
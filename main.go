@@ -5,12 +5,12 @@ import (
 	"os"
 
 	"metrics.k8s.io/cmd"
-	_ "metrics.k8s.io/kube"
+	"metrics.k8s.io/kube"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(kube.ExitCode(err))
 	}
 }